@@ -0,0 +1,186 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+)
+
+// aeGain is the fraction of the brightness error the software exposure
+// loop corrects per frame; exposure takes a few frames to settle, so a
+// full correction each frame would overshoot.
+const aeGain = 0.5
+
+// aeDeadband is how far, in luma levels, the mean may stray from the
+// target before the loop adjusts anything.
+const aeDeadband = 4
+
+type autoExposure struct {
+	target uint8
+
+	exposure, gain       ControlInfo
+	hasGain              bool
+	savedAuto, savedGain int32
+
+	// level runs from 0, the shortest exposure at the lowest gain, to 1, the
+	// longest exposure at the highest gain, with exposure filling the lower
+	// half when there is a gain control. Keeping it within those bounds is
+	// what keeps the loop from winding up while a control is at its limit.
+	level float64
+}
+
+// EnableSoftwareAE drives exposure, and gain when there is a gain
+// control, from the luma of every captured frame so that its mean settles
+// at target, for cameras whose own auto-exposure is poor or missing. The
+// camera's auto-exposure is switched off while it runs. It works from the
+// raw frames, so the device must capture a YUV format.
+func (dev *Device) EnableSoftwareAE(target uint8) error {
+
+	if _, ok := lumaHistogram(nil, dev.format, 0, 0, 0); !ok {
+		return fmt.Errorf("Unsupported format for software AE: %v", dev.format)
+	}
+
+	dev.mu.Lock()
+	running := dev.ae != nil
+	dev.mu.Unlock()
+
+	if running {
+		return errors.New("Software AE already enabled")
+	}
+
+	ae := &autoExposure{target: target, savedAuto: -1}
+
+	exposure, err := dev.ControlInfo(V4L2_CID_EXPOSURE_ABSOLUTE)
+	if err != nil {
+		return err
+	}
+	ae.exposure = exposure
+
+	if gain, err := dev.ControlInfo(V4L2_CID_GAIN); err == nil && gain.Max > gain.Min {
+		ae.gain = gain
+		ae.hasGain = true
+		ae.savedGain, _ = dev.GetControl(V4L2_CID_GAIN)
+	}
+
+	if v, err := dev.GetControl(V4L2_CID_EXPOSURE_AUTO); err == nil {
+		ae.savedAuto = v
+	}
+
+	if err := dev.SetControl(V4L2_CID_EXPOSURE_AUTO, V4L2_EXPOSURE_MANUAL); err != nil && err != ErrUnsupportedControl {
+		return err
+	}
+
+	// Start from wherever the camera is now rather than jumping.
+	if v, err := dev.GetControl(V4L2_CID_EXPOSURE_ABSOLUTE); err == nil {
+		ae.level = ae.exposureLevel(v)
+	}
+
+	dev.mu.Lock()
+	dev.ae = ae
+	dev.mu.Unlock()
+
+	return nil
+}
+
+// DisableSoftwareAE stops the software exposure loop and hands exposure
+// back to the camera's auto mode and gain as it was.
+func (dev *Device) DisableSoftwareAE() error {
+
+	dev.mu.Lock()
+	ae := dev.ae
+	dev.ae = nil
+	dev.mu.Unlock()
+
+	if ae == nil {
+		return nil
+	}
+
+	if ae.hasGain {
+		if err := dev.SetControl(V4L2_CID_GAIN, ae.savedGain); err != nil {
+			return err
+		}
+	}
+
+	if ae.savedAuto >= 0 {
+		return dev.SetControl(V4L2_CID_EXPOSURE_AUTO, ae.savedAuto)
+	}
+
+	return nil
+}
+
+// update moves exposure and gain one step toward the target brightness for
+// the frame just captured. Failed writes are left for the next frame.
+func (ae *autoExposure) update(dev *Device, frame []byte) {
+
+	h, ok := lumaHistogram(frame, dev.format, dev.width, dev.height, dev.stride())
+	if !ok {
+		return
+	}
+
+	var n, sum uint64
+	for l, c := range h {
+		n += uint64(c)
+		sum += uint64(l) * uint64(c)
+	}
+	if n == 0 {
+		return
+	}
+
+	diff := float64(ae.target) - float64(sum)/float64(n)
+	if diff > -aeDeadband && diff < aeDeadband {
+		return
+	}
+
+	level := ae.level + aeGain*diff/255
+	if level < 0 {
+		level = 0
+	} else if level > 1 {
+		level = 1
+	}
+
+	if level == ae.level {
+		return
+	}
+	ae.level = level
+
+	exposure, gain := ae.controls()
+
+	dev.SetControl(V4L2_CID_EXPOSURE_ABSOLUTE, exposure)
+	if ae.hasGain {
+		dev.SetControl(V4L2_CID_GAIN, gain)
+	}
+}
+
+// controls maps the loop's level onto exposure and gain values.
+func (ae *autoExposure) controls() (int32, int32) {
+
+	span := func(c ControlInfo, f float64) int32 {
+		return clampControl(c, c.Min+int32(f*float64(c.Max-c.Min)+0.5))
+	}
+
+	if !ae.hasGain {
+		return span(ae.exposure, ae.level), 0
+	}
+
+	if ae.level <= 0.5 {
+		return span(ae.exposure, ae.level*2), ae.gain.Min
+	}
+
+	return ae.exposure.Max, span(ae.gain, ae.level*2-1)
+}
+
+// exposureLevel is the level at which the loop sets exposure v, with gain
+// at its lowest.
+func (ae *autoExposure) exposureLevel(v int32) float64 {
+
+	c := ae.exposure
+	if c.Max <= c.Min {
+		return 0
+	}
+
+	f := float64(v-c.Min) / float64(c.Max-c.Min)
+	if ae.hasGain {
+		f /= 2
+	}
+
+	return f
+}
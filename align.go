@@ -0,0 +1,58 @@
+package v4l
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// pageSize is the alignment of USERPTR capture buffers. Drivers that DMA
+// into user memory commonly require buffers to start on a page boundary,
+// and reject others with EINVAL or, worse, quietly corrupt them.
+var pageSize = os.Getpagesize()
+
+// frameBuffer allocates a capture buffer of size bytes starting on a page
+// boundary. It is ordinary Go memory, which the garbage collector never
+// moves, so it can be returned to callers like any other slice.
+func frameBuffer(size int) []byte {
+
+	b := make([]byte, size+pageSize)
+	off := int(-uintptr(unsafe.Pointer(&b[0])) & uintptr(pageSize-1))
+
+	return b[off : off+size : off+size]
+}
+
+func pageAligned(b []byte) bool {
+	return toUintptr(b)&uintptr(pageSize-1) == 0
+}
+
+// bounceBuffer returns a page aligned buffer of size bytes for capturing
+// frames whose own memory is not aligned, such as slices of a caller's
+// buffer; the frame is copied out of it once dequeued. It is mapped outside
+// the Go heap, reused while the size stays the same, and unmapped by Close.
+func (dev *Device) bounceBuffer(size int) ([]byte, error) {
+
+	if len(dev.bounce) == size {
+		return dev.bounce, nil
+	}
+
+	dev.freeBounce()
+
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to allocate aligned buffer: %v", err.Error())
+	}
+
+	dev.bounce = b
+
+	return b, nil
+}
+
+func (dev *Device) freeBounce() {
+
+	if dev.bounce != nil {
+		syscall.Munmap(dev.bounce)
+		dev.bounce = nil
+	}
+}
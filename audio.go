@@ -0,0 +1,212 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+const (
+	V4L2_AUDCAP_STEREO uint32 = 0x00001
+	V4L2_AUDCAP_AVL           = 0x00002
+
+	V4L2_AUDMODE_AVL uint32 = 0x00001
+
+	VIDIOC_G_AUDIO   uintptr = 0x80345621
+	VIDIOC_S_AUDIO           = 0x40345622
+	VIDIOC_ENUMAUDIO         = 0xC0345641
+	VIDIOC_G_TUNER           = 0xC054561D
+	VIDIOC_S_TUNER           = 0x4054561E
+)
+
+const (
+	V4L2_TUNER_CAP_STEREO uint32 = 0x0010
+	V4L2_TUNER_CAP_SAP           = 0x0020
+
+	V4L2_TUNER_SUB_MONO   uint32 = 0x0001
+	V4L2_TUNER_SUB_STEREO        = 0x0002
+	V4L2_TUNER_SUB_SAP           = 0x0004
+
+	V4L2_TUNER_MODE_MONO   uint32 = 0
+	V4L2_TUNER_MODE_STEREO        = 1
+	V4L2_TUNER_MODE_SAP           = 2
+)
+
+type v4l2_tuner struct {
+	Index               uint32
+	Name                [32]uint8
+	Type                uint32
+	Capability          uint32
+	Rangelow, Rangehigh uint32
+	Rxsubchans, Audmode uint32
+	Signal, Afc         int32
+	Reserved            [4]uint32
+}
+
+type v4l2_audio struct {
+	Index      uint32
+	Name       [32]uint8
+	Capability uint32
+	Mode       uint32
+	Reserved   [2]uint32
+}
+
+// AudioInput is an audio input of a capture card, such as the sound of its
+// tuner or a line-in jack next to a composite input.
+type AudioInput struct {
+	Index      int
+	Name       string
+	Capability uint32
+	Mode       uint32
+}
+
+// Stereo reports whether the input can carry stereo. Whether a broadcast
+// is received as mono, stereo or SAP is a setting of the tuner rather than
+// of the audio input; see SetTunerAudioMode.
+func (a AudioInput) Stereo() bool {
+	return a.Capability&V4L2_AUDCAP_STEREO != 0
+}
+
+// AVL reports whether the input supports automatic volume levelling,
+// which V4L2_AUDMODE_AVL turns on.
+func (a AudioInput) AVL() bool {
+	return a.Capability&V4L2_AUDCAP_AVL != 0
+}
+
+func audioInput(a v4l2_audio) AudioInput {
+	return AudioInput{
+		Index:      int(a.Index),
+		Name:       cString(a.Name[:]),
+		Capability: a.Capability,
+		Mode:       a.Mode,
+	}
+}
+
+// AudioInputs enumerates the device's audio inputs. Devices without audio
+// have none.
+func (dev *handle) AudioInputs() ([]AudioInput, error) {
+
+	var inputs []AudioInput
+
+	for i := uint32(0); ; i++ {
+
+		a := v4l2_audio{Index: i}
+		b := toBytes(a)
+
+		if err := dev.ioctl(VIDIOC_ENUMAUDIO, toUintptr(b)); err != nil {
+			if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+				break
+			}
+			return nil, fmt.Errorf("Failed to enumerate audio inputs: %v", err.Error())
+		}
+
+		if err := fromBytes(b, &a); err != nil {
+			return nil, err
+		}
+
+		inputs = append(inputs, audioInput(a))
+	}
+
+	return inputs, nil
+}
+
+// Audio returns the audio input the device currently captures from.
+func (dev *handle) Audio() (AudioInput, error) {
+
+	var a v4l2_audio
+	b := toBytes(a)
+
+	if err := dev.ioctl(VIDIOC_G_AUDIO, toUintptr(b)); err != nil {
+		return AudioInput{}, fmt.Errorf("Failed to get audio input: %v", err.Error())
+	}
+
+	if err := fromBytes(b, &a); err != nil {
+		return AudioInput{}, err
+	}
+
+	return audioInput(a), nil
+}
+
+// SetAudio selects the audio input at index with mode, zero or
+// V4L2_AUDMODE_AVL.
+func (dev *Device) SetAudio(index int, mode uint32) error {
+
+	a := v4l2_audio{Index: uint32(index), Mode: mode}
+
+	if err := dev.ioctl(VIDIOC_S_AUDIO, toUintptr(toBytes(a))); err != nil {
+		return fmt.Errorf("Failed to set audio input: %v", err.Error())
+	}
+
+	return nil
+}
+
+// TunerAudio is how a tuner receives the sound of a broadcast.
+type TunerAudio struct {
+	// Mode is the V4L2_TUNER_MODE the tuner plays: mono, stereo or the
+	// second audio program.
+	Mode uint32
+
+	// Received holds the V4L2_TUNER_SUB bits of the audio the broadcast
+	// currently carries, and Capability the V4L2_TUNER_CAP bits of what
+	// the tuner can receive.
+	Received   uint32
+	Capability uint32
+}
+
+// Stereo reports whether the broadcast carries stereo sound.
+func (a TunerAudio) Stereo() bool {
+	return a.Received&V4L2_TUNER_SUB_STEREO != 0
+}
+
+// SAP reports whether the broadcast carries a second audio program.
+func (a TunerAudio) SAP() bool {
+	return a.Received&V4L2_TUNER_SUB_SAP != 0
+}
+
+func (dev *handle) getTuner(index int) (v4l2_tuner, error) {
+
+	t := v4l2_tuner{Index: uint32(index)}
+	b := toBytes(t)
+
+	if err := dev.ioctl(VIDIOC_G_TUNER, toUintptr(b)); err != nil {
+		return t, fmt.Errorf("Failed to get tuner: %v", err.Error())
+	}
+
+	if err := fromBytes(b, &t); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// TunerAudioMode returns how the tuner at index, usually 0, receives
+// broadcast sound.
+func (dev *handle) TunerAudioMode(index int) (TunerAudio, error) {
+
+	t, err := dev.getTuner(index)
+	if err != nil {
+		return TunerAudio{}, err
+	}
+
+	return TunerAudio{Mode: t.Audmode, Received: t.Rxsubchans, Capability: t.Capability}, nil
+}
+
+// SetTunerAudioMode makes the tuner at index play broadcast sound in mode,
+// V4L2_TUNER_MODE_MONO, V4L2_TUNER_MODE_STEREO or V4L2_TUNER_MODE_SAP.
+// Drivers fall back to what the broadcast carries, so a stereo mode plays
+// mono sound of a mono broadcast.
+func (dev *Device) SetTunerAudioMode(index int, mode uint32) error {
+
+	t, err := dev.getTuner(index)
+	if err != nil {
+		return err
+	}
+
+	t.Audmode = mode
+
+	if err := dev.ioctl(VIDIOC_S_TUNER, toUintptr(toBytes(t))); err != nil {
+		return fmt.Errorf("Failed to set tuner audio mode: %v", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+)
+
+// AverageFrames captures n frames and returns their per-pixel mean, which
+// suppresses sensor noise on a static scene.
+func (dev *Device) AverageFrames(n int) (*image.RGBA, error) {
+
+	if n < 1 {
+		return nil, fmt.Errorf("Invalid frame count: %d", n)
+	}
+
+	var sum []uint32
+	var im *image.RGBA
+
+	for i := 0; i < n; i++ {
+
+		frame, err := dev.GetFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if sum == nil {
+			sum = make([]uint32, frame.Rect.Dx()*frame.Rect.Dy()*4)
+			im = image.NewRGBA(frame.Rect)
+		} else if frame.Rect != im.Rect {
+			return nil, fmt.Errorf("Frame size changed from %v to %v", im.Rect, frame.Rect)
+		}
+
+		// Frames captured without a copy keep the driver's line padding,
+		// so each line is read at its own offset.
+		j := 0
+		for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+			p := frame.PixOffset(frame.Rect.Min.X, y)
+			for _, v := range frame.Pix[p : p+frame.Rect.Dx()*4] {
+				sum[j] += uint32(v)
+				j++
+			}
+		}
+	}
+
+	for j, v := range sum {
+		im.Pix[j] = uint8((v + uint32(n)/2) / uint32(n))
+	}
+
+	return im, nil
+}
+
+// MedianFrames captures n frames and returns their per-pixel median, which
+// unlike the mean ignores outliers such as a passing object or hot pixel.
+func (dev *Device) MedianFrames(n int) (*image.RGBA, error) {
+
+	if n < 1 {
+		return nil, fmt.Errorf("Invalid frame count: %d", n)
+	}
+
+	frames := make([]*image.RGBA, n)
+
+	for i := range frames {
+
+		frame, err := dev.GetFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 && frame.Rect != frames[0].Rect {
+			return nil, fmt.Errorf("Frame size changed from %v to %v", frames[0].Rect, frame.Rect)
+		}
+
+		frames[i] = frame
+	}
+
+	im := image.NewRGBA(frames[0].Rect)
+
+	// The samples of a pixel are sorted in place, on the stack for the
+	// usual handful of frames, so nothing is allocated per pixel.
+	var stack [64]uint8
+	samples := stack[:]
+	if n > len(stack) {
+		samples = make([]uint8, n)
+	}
+	samples = samples[:n]
+
+	// Frames captured without a copy keep the driver's line padding, so
+	// each is indexed by its own stride.
+	for y := im.Rect.Min.Y; y < im.Rect.Max.Y; y++ {
+		for x := im.Rect.Min.X; x < im.Rect.Max.X; x++ {
+
+			p := im.PixOffset(x, y)
+
+			for c := 0; c < 4; c++ {
+
+				for i, frame := range frames {
+					samples[i] = frame.Pix[frame.PixOffset(x, y)+c]
+				}
+
+				insertionSort(samples)
+
+				im.Pix[p+c] = samples[n/2]
+			}
+		}
+	}
+
+	return im, nil
+}
+
+// insertionSort sorts s in ascending order, which for the few samples of
+// a median beats a general sort.
+func insertionSort(s []uint8) {
+
+	for i := 1; i < len(s); i++ {
+		v := s[i]
+		j := i
+		for ; j > 0 && s[j-1] > v; j-- {
+			s[j] = s[j-1]
+		}
+		s[j] = v
+	}
+}
@@ -0,0 +1,60 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestInsertionSort(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 20; n++ {
+
+		s := make([]uint8, n)
+		r.Read(s)
+
+		want := append([]uint8(nil), s...)
+		sort.Slice(want, func(a, b int) bool { return want[a] < want[b] })
+
+		insertionSort(s)
+
+		for i := range s {
+			if s[i] != want[i] {
+				t.Fatalf("n=%d: got %v, want %v", n, s, want)
+			}
+		}
+	}
+}
+
+func TestStaticSceneStacks(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	want, err := dev.GetFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, stack := range map[string]func(int) (*image.RGBA, error){
+		"mean":   dev.AverageFrames,
+		"median": dev.MedianFrames,
+	} {
+
+		im, err := stack(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if im.Rect != want.Rect || !bytes.Equal(im.Pix, want.Pix) {
+			t.Errorf("%s of a static scene differs from a single frame", name)
+		}
+	}
+}
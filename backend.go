@@ -0,0 +1,80 @@
+package v4l
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Backend performs the system calls behind a device. The default issues
+// them on the real device node; a fake can stand in for a camera so that
+// capture can run without one. Ioctl's arg points to the request's struct
+// laid out as the kernel expects it, and the fake fills it in the same way
+// a driver would.
+type Backend interface {
+	Open(path string, flags int) (int, error)
+	Close(fd int) error
+	Ioctl(fd int, req, arg uintptr) error
+	Poll(fd int, events int16, timeout time.Duration) (int16, error)
+}
+
+// ReadBackend is a Backend that can also read frames from a device, which
+// devices that only support read() I/O rather than streaming need.
+type ReadBackend interface {
+	Backend
+	Read(fd int, p []byte) (int, error)
+}
+
+type systemBackend struct{}
+
+func (systemBackend) Open(path string, flags int) (int, error) {
+	return syscall.Open(path, flags, 0666)
+}
+
+func (systemBackend) Close(fd int) error {
+	return syscall.Close(fd)
+}
+
+func (systemBackend) Ioctl(fd int, req, arg uintptr) error {
+	return ioctl(fd, req, arg)
+}
+
+func (systemBackend) Poll(fd int, events int16, timeout time.Duration) (int16, error) {
+	return poll(fd, events, timeout)
+}
+
+func (systemBackend) Read(fd int, p []byte) (int, error) {
+
+	n, err := syscall.Read(fd, p)
+	for err == syscall.EINTR {
+		n, err = syscall.Read(fd, p)
+	}
+	if err != nil {
+		return n, os.NewSyscallError("read", err)
+	}
+
+	return n, nil
+}
+
+// MmapBackend is a Backend that can also map a device's buffers into
+// memory, which devices that do not take USERPTR buffers, such as many
+// hardware JPEG decoders, need.
+type MmapBackend interface {
+	Backend
+	Mmap(fd int, offset int64, length int) ([]byte, error)
+	Munmap(b []byte) error
+}
+
+func (systemBackend) Mmap(fd int, offset int64, length int) ([]byte, error) {
+
+	b, err := syscall.Mmap(fd, offset, length, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+
+	return b, nil
+}
+
+func (systemBackend) Munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
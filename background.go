@@ -0,0 +1,192 @@
+package v4l
+
+import (
+	"errors"
+	"image"
+	"sync"
+)
+
+type backgroundCapture struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	front *image.RGBA
+	back  *image.RGBA
+	info  FrameInfo
+	ready bool
+	err   error
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// EnableBackgroundCapture starts a goroutine that keeps capturing into a
+// pair of images, swapping them as each frame completes. While it runs
+// GetFrame returns a copy of the most recent frame without waiting on the
+// driver.
+func (dev *Device) EnableBackgroundCapture() error {
+
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+
+	if dev.background != nil {
+		return errors.New("Background capture already enabled")
+	}
+
+	if err := dev.checkConvert(); err != nil {
+		return err
+	}
+
+	bg := &backgroundCapture{
+		front: image.NewRGBA(dev.bounds()),
+		back:  image.NewRGBA(dev.bounds()),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	bg.cond = sync.NewCond(&bg.mu)
+
+	dev.background = bg
+
+	go bg.run(dev)
+
+	return nil
+}
+
+// DisableBackgroundCapture stops the background capture goroutine, waiting
+// for the frame it is capturing.
+func (dev *Device) DisableBackgroundCapture() {
+
+	dev.mu.Lock()
+	bg := dev.background
+	dev.background = nil
+	dev.mu.Unlock()
+
+	if bg != nil {
+		bg.close(dev)
+	}
+}
+
+// CurrentFrame returns a copy of the most recent frame captured in the
+// background, waiting only for the very first one.
+func (dev *Device) CurrentFrame() (*image.RGBA, error) {
+
+	dev.mu.Lock()
+	bg := dev.background
+	dev.mu.Unlock()
+
+	if bg == nil {
+		return nil, errors.New("Background capture not enabled")
+	}
+
+	im, _, err := bg.current()
+
+	return im, err
+}
+
+// current returns a copy of the front image and the info of its frame.
+func (bg *backgroundCapture) current() (*image.RGBA, FrameInfo, error) {
+
+	bg.mu.Lock()
+	defer bg.mu.Unlock()
+
+	for !bg.ready && bg.err == nil {
+		bg.cond.Wait()
+	}
+
+	if bg.err != nil {
+		return nil, FrameInfo{}, bg.err
+	}
+
+	im := image.NewRGBA(bg.front.Rect)
+	copy(im.Pix, bg.front.Pix)
+
+	return im, bg.info, nil
+}
+
+func (bg *backgroundCapture) run(dev *Device) {
+
+	defer close(bg.done)
+
+	for {
+
+		select {
+		case <-bg.stop:
+			bg.stopped()
+			return
+		default:
+		}
+
+		frame, info, err := dev.readFrame()
+
+		// A capture failing because close stopped streaming ends the
+		// goroutine without recording the error.
+		select {
+		case <-bg.stop:
+			bg.stopped()
+			return
+		default:
+		}
+
+		if err == ErrFrameCorrupt {
+			continue
+		}
+		if err == nil {
+			if r := dev.bounds(); bg.back.Rect != r {
+				bg.back = image.NewRGBA(r)
+			}
+			dev.convert(frame, bg.back)
+		}
+
+		bg.mu.Lock()
+		if err != nil {
+			bg.err = err
+		} else {
+			bg.front, bg.back = bg.back, bg.front
+			bg.info = info
+			bg.ready = true
+		}
+		bg.cond.Broadcast()
+		bg.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// close stops the goroutine and waits for it. Streaming is stopped first,
+// as for LatestFrame, so that a goroutine blocked on a frame that never
+// comes gets its buffer back, and started again afterwards for the
+// captures that follow.
+func (bg *backgroundCapture) close(dev *Device) {
+
+	close(bg.stop)
+
+	if dev.readIO || dev.config.noStreamOn || !dev.streaming {
+		<-bg.done
+		return
+	}
+
+	b := toBytes(dev.bufType)
+
+	dev.ioctl(VIDIOC_STREAMOFF, toUintptr(b))
+	<-bg.done
+
+	// STREAMOFF returns buffers to the unprepared state.
+	dev.prepared = 0
+
+	if err := dev.ioctl(VIDIOC_STREAMON, toUintptr(b)); err != nil {
+		dev.streaming = false
+		return
+	}
+
+	dev.prepareSpare()
+}
+
+// stopped fails the callers waiting for a frame once the goroutine has
+// stopped.
+func (bg *backgroundCapture) stopped() {
+
+	bg.mu.Lock()
+	bg.err = errors.New("Background capture stopped")
+	bg.cond.Broadcast()
+	bg.mu.Unlock()
+}
@@ -0,0 +1,173 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+)
+
+var (
+	V4L2_PIX_FMT_SRGGB10P = FourCC("pRAA")
+	V4L2_PIX_FMT_SGRBG10P = FourCC("pgAA")
+	V4L2_PIX_FMT_SGBRG10P = FourCC("pGAA")
+	V4L2_PIX_FMT_SBGGR10P = FourCC("pBAA")
+)
+
+// BayerPattern names the colors of the top left 2x2 block of a Bayer
+// mosaic, row by row.
+type BayerPattern int
+
+const (
+	RGGB BayerPattern = iota
+	GRBG
+	GBRG
+	BGGR
+)
+
+// Index of the red and blue sample within a 2x2 block, counted row by row.
+// The other two are green.
+var bayerRedBlue = [...][2]int{
+	RGGB: {0, 3},
+	GRBG: {1, 2},
+	GBRG: {2, 1},
+	BGGR: {3, 0},
+}
+
+func bayer10Pattern(format Format) (BayerPattern, bool) {
+
+	switch format {
+	case V4L2_PIX_FMT_SRGGB10P:
+		return RGGB, true
+	case V4L2_PIX_FMT_SGRBG10P:
+		return GRBG, true
+	case V4L2_PIX_FMT_SGBRG10P:
+		return GBRG, true
+	case V4L2_PIX_FMT_SBGGR10P:
+		return BGGR, true
+	}
+
+	return 0, false
+}
+
+// bayer10Sample reads pixel x of line y from a 10-bit packed raw frame.
+// Every four pixels take five bytes: the high eight bits of each pixel,
+// then one byte holding their low two bits, first pixel in the lowest bits.
+func bayer10Sample(frame []byte, stride, x, y int) uint16 {
+
+	i := y*stride + (x/4)*5
+	shift := uint(x%4) * 2
+
+	return uint16(frame[i+x%4])<<2 | uint16(frame[i+4]>>shift)&3
+}
+
+// UnpackBayer10 expands a 10-bit packed raw frame into one 16-bit sample
+// per pixel, keeping the mosaic. Samples keep their 10-bit range.
+func UnpackBayer10(frame []byte, width, height, stride int) (*image.Gray16, error) {
+
+	if stride < width*5/4 || len(frame) < stride*(height-1)+width*5/4 {
+		return nil, fmt.Errorf("Frame too short for %dx%d: %d bytes", width, height, len(frame))
+	}
+
+	im := image.NewGray16(image.Rect(0, 0, width, height))
+
+	p := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := bayer10Sample(frame, stride, x, y)
+			im.Pix[p+0], im.Pix[p+1] = uint8(v>>8), uint8(v)
+			p += 2
+		}
+	}
+
+	return im, nil
+}
+
+// DemosaicBayer turns an unpacked mosaic with samples of the given bit
+// depth into color by giving each pixel the red, blue and mean green of the
+// 2x2 block it falls in.
+func DemosaicBayer(raw *image.Gray16, pattern BayerPattern, bits uint) *image.RGBA {
+
+	b := raw.Bounds()
+	im := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	demosaic(func(x, y int) uint16 {
+		return raw.Gray16At(b.Min.X+x, b.Min.Y+y).Y
+	}, b.Dx(), b.Dy(), 1, pattern, bits, im)
+
+	return im
+}
+
+func demosaic(sample func(x, y int) uint16, width, height, scale int, pattern BayerPattern, bits uint, im *image.RGBA) {
+
+	rb := bayerRedBlue[pattern]
+	shift := bits - 8
+	b := im.Bounds()
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+
+		qy := (y * scale) &^ 1
+		if qy+1 >= height && qy > 0 {
+			qy -= 2
+		}
+
+		for x := 0; x < b.Dx(); x++ {
+
+			qx := (x * scale) &^ 1
+			if qx+1 >= width && qx > 0 {
+				qx -= 2
+			}
+
+			q := [4]uint16{
+				sample(qx, qy), sample(qx+1, qy),
+				sample(qx, qy+1), sample(qx+1, qy+1),
+			}
+
+			g := (uint32(q[0]) + uint32(q[1]) + uint32(q[2]) + uint32(q[3]) -
+				uint32(q[rb[0]]) - uint32(q[rb[1]])) / 2
+
+			im.Pix[p+0] = uint8(q[rb[0]] >> shift)
+			im.Pix[p+1] = uint8(g >> shift)
+			im.Pix[p+2] = uint8(q[rb[1]] >> shift)
+			im.Pix[p+3] = 0xff
+			p += 4
+		}
+	}
+
+}
+
+// shiftPattern gives the pattern of a mosaic cropped dx columns and dy
+// lines in from the original.
+func shiftPattern(pattern BayerPattern, dx, dy int) BayerPattern {
+
+	if dx%2 == 1 {
+		pattern = [...]BayerPattern{RGGB: GRBG, GRBG: RGGB, GBRG: BGGR, BGGR: GBRG}[pattern]
+	}
+
+	if dy%2 == 1 {
+		pattern = [...]BayerPattern{RGGB: GBRG, GRBG: BGGR, GBRG: RGGB, BGGR: GRBG}[pattern]
+	}
+
+	return pattern
+}
+
+func bayer10ToImage(frame []byte, width, height, stride, scale int, pattern BayerPattern, im *image.RGBA) {
+	demosaic(func(x, y int) uint16 {
+		return bayer10Sample(frame, stride, x, y)
+	}, width, height, scale, pattern, 10, im)
+}
+
+// GetFrameBayer captures a frame from a 10-bit packed raw device and
+// returns its unpacked mosaic without demosaicing.
+func (dev *Device) GetFrameBayer() (*image.Gray16, error) {
+
+	if _, ok := bayer10Pattern(dev.format); !ok {
+		return nil, fmt.Errorf("Unsupported format for Bayer: %v", dev.format)
+	}
+
+	frame, _, err := dev.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	return UnpackBayer10(frame, dev.width, dev.height, dev.stride())
+}
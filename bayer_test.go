@@ -0,0 +1,68 @@
+package v4l
+
+import (
+	"image/color"
+	"testing"
+)
+
+// packBayer10 packs lines of 10-bit samples four pixels to five bytes,
+// the lines stride bytes apart.
+func packBayer10(lines [][]uint16, stride int) []byte {
+
+	frame := make([]byte, stride*len(lines))
+
+	for y, line := range lines {
+		row := frame[y*stride:]
+		for x, v := range line {
+			i := (x / 4) * 5
+			row[i+x%4] = uint8(v >> 2)
+			row[i+4] |= uint8(v&3) << (uint(x%4) * 2)
+		}
+	}
+
+	return frame
+}
+
+func TestUnpackBayer10(t *testing.T) {
+
+	lines := [][]uint16{
+		{0x3ff, 0x000, 0x155, 0x2aa, 0x001, 0x002, 0x003, 0x200},
+		{0x100, 0x0ff, 0x301, 0x3fe, 0x07c, 0x183, 0x2d2, 0x0a5},
+	}
+
+	// Two groups of four pixels take ten bytes; the lines are padded.
+	const width, height, stride = 8, 2, 12
+
+	im, err := UnpackBayer10(packBayer10(lines, stride), width, height, stride)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y, line := range lines {
+		for x, want := range line {
+			if got := im.Gray16At(x, y).Y; got != want {
+				t.Errorf("pixel %d,%d: got %#x, want %#x", x, y, got, want)
+			}
+		}
+	}
+
+	if _, err := UnpackBayer10(make([]byte, stride), width, height, stride); err == nil {
+		t.Error("short frame unpacked")
+	}
+}
+
+func TestDemosaicBayer10(t *testing.T) {
+
+	// A 2x2 RGGB block of full red, half green and no blue.
+	frame := packBayer10([][]uint16{{0x3ff, 0x200}, {0x200, 0}}, 5)
+
+	im, err := UnpackBayer10(frame, 2, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := color.RGBA{0xff, 0x80, 0, 0xff}
+	if got := DemosaicBayer(im, RGGB, 10).RGBAAt(1, 1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
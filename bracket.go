@@ -0,0 +1,106 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"time"
+)
+
+// bracketSettle is how many frames BracketCapture discards after each
+// exposure change. Cameras apply a new exposure a frame or two after it is
+// written, as frames already being exposed finish with the old one.
+const bracketSettle = 2
+
+// BracketFrame is a frame of an exposure bracket.
+type BracketFrame struct {
+	Image *image.RGBA
+	Info  FrameInfo
+
+	// EV is the step requested relative to the exposure at the start, and
+	// Exposure the exposure time the camera was set to for it, which
+	// differs from the request where it hit the control's limits.
+	EV       float64
+	Exposure time.Duration
+}
+
+// BracketCapture captures one frame at each of evs, exposure steps
+// relative to the current exposure such as -2, 0 and +2, for merging into
+// an HDR image. Exposure is switched to manual for the bracket and then
+// restored along with the auto exposure mode. After each change the
+// frames still exposed with the previous setting are discarded, so the
+// bracket takes several frame intervals per step.
+func (dev *Device) BracketCapture(evs ...float64) ([]BracketFrame, error) {
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, err
+	}
+
+	dev.mu.Lock()
+	ae, bg := dev.ae, dev.background
+	dev.mu.Unlock()
+
+	if ae != nil {
+		return nil, errors.New("Cannot bracket while software AE is enabled")
+	}
+
+	if bg != nil {
+		return nil, errors.New("Cannot bracket during background capture")
+	}
+
+	c, err := dev.ControlInfo(V4L2_CID_EXPOSURE_ABSOLUTE)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := dev.GetControl(V4L2_CID_EXPOSURE_ABSOLUTE)
+	if err != nil {
+		return nil, err
+	}
+
+	auto, autoErr := dev.GetControl(V4L2_CID_EXPOSURE_AUTO)
+
+	if err := dev.SetControl(V4L2_CID_EXPOSURE_AUTO, V4L2_EXPOSURE_MANUAL); err != nil && err != ErrUnsupportedControl {
+		return nil, err
+	}
+
+	defer func() {
+		dev.SetControl(V4L2_CID_EXPOSURE_ABSOLUTE, base)
+		if autoErr == nil {
+			dev.SetControl(V4L2_CID_EXPOSURE_AUTO, auto)
+		}
+	}()
+
+	frames := make([]BracketFrame, 0, len(evs))
+
+	for _, ev := range evs {
+
+		v := clampControl(c, int32(math.Round(float64(base)*math.Exp2(ev))))
+
+		if err := dev.SetControl(V4L2_CID_EXPOSURE_ABSOLUTE, v); err != nil {
+			return frames, fmt.Errorf("Failed to set exposure for %+.1f EV: %v", ev, err.Error())
+		}
+
+		if err := dev.WarmUp(bracketSettle); err != nil {
+			return frames, err
+		}
+
+		frame, info, err := dev.readFrame()
+		if err != nil {
+			return frames, err
+		}
+
+		im := image.NewRGBA(dev.bounds())
+		dev.convert(frame, im)
+
+		frames = append(frames, BracketFrame{
+			Image:    dev.transformed(im, info),
+			Info:     info,
+			EV:       ev,
+			Exposure: time.Duration(v) * exposureUnit,
+		})
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,189 @@
+package v4l
+
+import (
+	"time"
+)
+
+const (
+	V4L2_CID_EXPOSURE_ABSOLUTE = V4L2_CID_CAMERA_CLASS_BASE + 2
+	V4L2_CID_PAN_RELATIVE      = V4L2_CID_CAMERA_CLASS_BASE + 4
+	V4L2_CID_TILT_RELATIVE     = V4L2_CID_CAMERA_CLASS_BASE + 5
+	V4L2_CID_PAN_RESET         = V4L2_CID_CAMERA_CLASS_BASE + 6
+	V4L2_CID_TILT_RESET        = V4L2_CID_CAMERA_CLASS_BASE + 7
+	V4L2_CID_PAN_ABSOLUTE      = V4L2_CID_CAMERA_CLASS_BASE + 8
+	V4L2_CID_TILT_ABSOLUTE     = V4L2_CID_CAMERA_CLASS_BASE + 9
+	V4L2_CID_ZOOM_ABSOLUTE     = V4L2_CID_CAMERA_CLASS_BASE + 13
+	V4L2_CID_ZOOM_RELATIVE     = V4L2_CID_CAMERA_CLASS_BASE + 14
+	V4L2_CID_EXPOSURE_METERING = V4L2_CID_CAMERA_CLASS_BASE + 25
+	V4L2_CID_SCENE_MODE        = V4L2_CID_CAMERA_CLASS_BASE + 26
+
+	V4L2_EXPOSURE_AUTO              int32 = 0
+	V4L2_EXPOSURE_MANUAL                  = 1
+	V4L2_EXPOSURE_SHUTTER_PRIORITY        = 2
+	V4L2_EXPOSURE_APERTURE_PRIORITY       = 3
+
+	V4L2_EXPOSURE_METERING_AVERAGE         int32 = 0
+	V4L2_EXPOSURE_METERING_CENTER_WEIGHTED       = 1
+	V4L2_EXPOSURE_METERING_SPOT                  = 2
+	V4L2_EXPOSURE_METERING_MATRIX                = 3
+
+	V4L2_SCENE_MODE_NONE         int32 = 0
+	V4L2_SCENE_MODE_BACKLIGHT          = 1
+	V4L2_SCENE_MODE_BEACH_SNOW         = 2
+	V4L2_SCENE_MODE_CANDLE_LIGHT       = 3
+	V4L2_SCENE_MODE_DAWN_DUSK          = 4
+	V4L2_SCENE_MODE_FALL_COLORS        = 5
+	V4L2_SCENE_MODE_FIREWORKS          = 6
+	V4L2_SCENE_MODE_LANDSCAPE          = 7
+	V4L2_SCENE_MODE_NIGHT              = 8
+	V4L2_SCENE_MODE_PARTY_INDOOR       = 9
+	V4L2_SCENE_MODE_PORTRAIT           = 10
+	V4L2_SCENE_MODE_SPORTS             = 11
+	V4L2_SCENE_MODE_SUNSET             = 12
+	V4L2_SCENE_MODE_TEXT               = 13
+)
+
+// V4L2_CID_EXPOSURE_ABSOLUTE counts in units of 100µs.
+const exposureUnit = 100 * time.Microsecond
+
+// SetExposure switches exposure to manual and sets the exposure time,
+// clamped to the range the device supports.
+func (dev *Device) SetExposure(d time.Duration) error {
+
+	c, err := dev.ControlInfo(V4L2_CID_EXPOSURE_ABSOLUTE)
+	if err != nil {
+		return err
+	}
+
+	if err := dev.SetControl(V4L2_CID_EXPOSURE_AUTO, V4L2_EXPOSURE_MANUAL); err != nil && err != ErrUnsupportedControl {
+		return err
+	}
+
+	// Durations beyond the control's range would wrap around in int32, so
+	// they are limited to it first.
+	v := int64((d + exposureUnit/2) / exposureUnit)
+	if v < int64(c.Min) {
+		v = int64(c.Min)
+	} else if v > int64(c.Max) {
+		v = int64(c.Max)
+	}
+
+	return dev.SetControl(V4L2_CID_EXPOSURE_ABSOLUTE, clampControl(c, int32(v)))
+}
+
+// Exposure returns the manual exposure time.
+func (dev *Device) Exposure() (time.Duration, error) {
+
+	v, err := dev.GetControl(V4L2_CID_EXPOSURE_ABSOLUTE)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(v) * exposureUnit, nil
+}
+
+// SetPan turns the camera to an absolute pan position in arc seconds,
+// clamped to the supported range.
+func (dev *Device) SetPan(v int32) error {
+	return dev.setClamped(V4L2_CID_PAN_ABSOLUTE, v)
+}
+
+// Pan returns the absolute pan position in arc seconds.
+func (dev *Device) Pan() (int32, error) {
+	return dev.GetControl(V4L2_CID_PAN_ABSOLUTE)
+}
+
+// SetTilt turns the camera to an absolute tilt position in arc seconds,
+// clamped to the supported range.
+func (dev *Device) SetTilt(v int32) error {
+	return dev.setClamped(V4L2_CID_TILT_ABSOLUTE, v)
+}
+
+// Tilt returns the absolute tilt position in arc seconds.
+func (dev *Device) Tilt() (int32, error) {
+	return dev.GetControl(V4L2_CID_TILT_ABSOLUTE)
+}
+
+// SetZoom sets the absolute zoom position, clamped to the supported range.
+func (dev *Device) SetZoom(v int32) error {
+	return dev.setClamped(V4L2_CID_ZOOM_ABSOLUTE, v)
+}
+
+// Zoom returns the absolute zoom position.
+func (dev *Device) Zoom() (int32, error) {
+	return dev.GetControl(V4L2_CID_ZOOM_ABSOLUTE)
+}
+
+// MovePan, MoveTilt and MoveZoom go through the relative controls, which
+// move the camera by the given amount from wherever it currently is and
+// read back as zero afterwards.
+func (dev *Device) MovePan(delta int32) error {
+	return dev.setClamped(V4L2_CID_PAN_RELATIVE, delta)
+}
+
+func (dev *Device) MoveTilt(delta int32) error {
+	return dev.setClamped(V4L2_CID_TILT_RELATIVE, delta)
+}
+
+func (dev *Device) MoveZoom(delta int32) error {
+	return dev.setClamped(V4L2_CID_ZOOM_RELATIVE, delta)
+}
+
+// ResetPanTilt returns the camera to its home position.
+func (dev *Device) ResetPanTilt() error {
+
+	if err := dev.SetControl(V4L2_CID_PAN_RESET, 1); err != nil {
+		return err
+	}
+
+	return dev.SetControl(V4L2_CID_TILT_RESET, 1)
+}
+
+func (dev *Device) setClamped(id uint32, v int32) error {
+
+	c, err := dev.ControlInfo(id)
+	if err != nil {
+		return err
+	}
+
+	return dev.SetControl(id, clampControl(c, v))
+}
+
+// clampControl limits v to the control's range, rounding to its step.
+func clampControl(c ControlInfo, v int32) int32 {
+
+	if v < c.Min {
+		v = c.Min
+	} else if v > c.Max {
+		v = c.Max
+	}
+
+	if c.Step > 1 {
+		v = c.Min + (v-c.Min)/c.Step*c.Step
+	}
+
+	return v
+}
+
+// SetSceneMode tunes auto-exposure and the other automatic controls for
+// one of the V4L2_SCENE_MODE scenes, such as V4L2_SCENE_MODE_PORTRAIT.
+// MenuItems lists the scenes the device offers.
+func (dev *Device) SetSceneMode(mode int32) error {
+	return dev.setMenuControl(V4L2_CID_SCENE_MODE, mode)
+}
+
+// SceneMode returns the V4L2_SCENE_MODE scene in use.
+func (dev *Device) SceneMode() (int32, error) {
+	return dev.GetControl(V4L2_CID_SCENE_MODE)
+}
+
+// SetExposureMetering selects which part of the frame auto-exposure
+// weighs, one of the V4L2_EXPOSURE_METERING modes.
+func (dev *Device) SetExposureMetering(mode int32) error {
+	return dev.setMenuControl(V4L2_CID_EXPOSURE_METERING, mode)
+}
+
+// ExposureMetering returns the V4L2_EXPOSURE_METERING mode in use.
+func (dev *Device) ExposureMetering() (int32, error) {
+	return dev.GetControl(V4L2_CID_EXPOSURE_METERING)
+}
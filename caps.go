@@ -0,0 +1,186 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotCaptureDevice is returned by Open for a node that cannot capture the
+// requested buffer type, such as the metadata or output node a camera
+// exposes next to its capture node.
+var ErrNotCaptureDevice = errors.New("Not a capture device")
+
+const (
+	V4L2_CAP_VIDEO_CAPTURE        uint32 = 0x00000001
+	V4L2_CAP_VIDEO_OUTPUT                = 0x00000002
+	V4L2_CAP_VIDEO_OVERLAY               = 0x00000004
+	V4L2_CAP_VBI_CAPTURE                 = 0x00000010
+	V4L2_CAP_VBI_OUTPUT                  = 0x00000020
+	V4L2_CAP_VIDEO_CAPTURE_MPLANE        = 0x00001000
+	V4L2_CAP_VIDEO_OUTPUT_MPLANE         = 0x00002000
+	V4L2_CAP_VIDEO_M2M_MPLANE            = 0x00004000
+	V4L2_CAP_VIDEO_M2M                   = 0x00008000
+	V4L2_CAP_TUNER                       = 0x00010000
+	V4L2_CAP_AUDIO                       = 0x00020000
+	V4L2_CAP_RADIO                       = 0x00040000
+	V4L2_CAP_SDR_CAPTURE                 = 0x00100000
+	V4L2_CAP_META_CAPTURE                = 0x00800000
+	V4L2_CAP_READWRITE                   = 0x01000000
+	V4L2_CAP_STREAMING                   = 0x04000000
+	V4L2_CAP_DEVICE_CAPS                 = 0x80000000
+)
+
+type v4l2_capability struct {
+	Driver       [16]uint8
+	Card         [32]uint8
+	BusInfo      [32]uint8
+	Version      uint32
+	Capabilities uint32
+	DeviceCaps   uint32
+	Reserved     [3]uint32
+}
+
+func (dev *handle) queryCap() (v4l2_capability, error) {
+
+	var c v4l2_capability
+
+	b := toBytes(c)
+
+	if err := dev.ioctl(VIDIOC_QUERYCAP, toUintptr(b)); err != nil {
+		return c, err
+	}
+
+	if err := fromBytes(b, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// hasCap tests the capabilities of the opened node itself, which is what
+// DeviceCaps describes when the driver fills it in, rather than those of
+// the physical device as a whole.
+func (dev *handle) hasCap(flag uint32) bool {
+	return dev.nodeCaps()&flag != 0
+}
+
+func (dev *handle) nodeCaps() uint32 {
+
+	if caps := dev.caps.Capabilities; caps&V4L2_CAP_DEVICE_CAPS == 0 {
+		return caps
+	}
+
+	return dev.caps.DeviceCaps
+}
+
+func (dev *handle) CanCapture() bool {
+	return dev.hasCap(V4L2_CAP_VIDEO_CAPTURE | V4L2_CAP_VIDEO_CAPTURE_MPLANE)
+}
+
+// capturesType reports whether the node can capture buffers of type t.
+func (dev *handle) capturesType(t uint32) bool {
+
+	switch t {
+	case V4L2_BUF_TYPE_VIDEO_CAPTURE:
+		return dev.CanCapture()
+	case V4L2_BUF_TYPE_VBI_CAPTURE:
+		return dev.hasCap(V4L2_CAP_VBI_CAPTURE)
+	case V4L2_BUF_TYPE_SDR_CAPTURE:
+		return dev.hasCap(V4L2_CAP_SDR_CAPTURE)
+	case V4L2_BUF_TYPE_META_CAPTURE:
+		return dev.hasCap(V4L2_CAP_META_CAPTURE)
+	}
+
+	return true
+}
+
+func (dev *handle) CanOutput() bool {
+	return dev.hasCap(V4L2_CAP_VIDEO_OUTPUT | V4L2_CAP_VIDEO_OUTPUT_MPLANE)
+}
+
+func (dev *handle) CanStream() bool {
+	return dev.hasCap(V4L2_CAP_STREAMING)
+}
+
+func (dev *handle) CanReadWrite() bool {
+	return dev.hasCap(V4L2_CAP_READWRITE)
+}
+
+func (dev *handle) HasTuner() bool {
+	return dev.hasCap(V4L2_CAP_TUNER)
+}
+
+func (dev *handle) HasAudio() bool {
+	return dev.hasCap(V4L2_CAP_AUDIO)
+}
+
+func (dev *handle) IsMultiPlanar() bool {
+	return dev.hasCap(V4L2_CAP_VIDEO_CAPTURE_MPLANE | V4L2_CAP_VIDEO_OUTPUT_MPLANE | V4L2_CAP_VIDEO_M2M_MPLANE)
+}
+
+func (dev *handle) IsMemToMem() bool {
+	return dev.hasCap(V4L2_CAP_VIDEO_M2M | V4L2_CAP_VIDEO_M2M_MPLANE)
+}
+
+// DriverVersion decodes the driver version QUERYCAP reports, packed as
+// KERNEL_VERSION(major, minor, patch).
+func (dev *handle) DriverVersion() (int, int, int) {
+	v := dev.caps.Version
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)
+}
+
+// DeviceInfo sums up an opened device for logging: what QUERYCAP reported
+// and the stream Open set up.
+type DeviceInfo struct {
+	Path    string
+	Driver  string
+	Card    string
+	BusInfo string
+	Version string
+
+	// Capabilities are those of the opened node, as hasCap tests them.
+	Capabilities uint32
+
+	Format       Format
+	Width        int
+	Height       int
+	BytesPerLine int
+	ImageSize    int
+	Field        uint32
+
+	Buffers int
+
+	// Memory is how frames are transferred: "USERPTR" streaming, or
+	// "read" for devices without streaming I/O.
+	Memory string
+}
+
+// Info returns everything Open learned about the device in one value. It
+// is put together from what the Device already holds, so it issues no
+// ioctls and reflects the format after any source change.
+func (dev *Device) Info() DeviceInfo {
+
+	major, minor, patch := dev.DriverVersion()
+
+	memory := "USERPTR"
+	if dev.readIO {
+		memory = "read"
+	}
+
+	return DeviceInfo{
+		Path:         dev.device,
+		Driver:       cString(dev.caps.Driver[:]),
+		Card:         cString(dev.caps.Card[:]),
+		BusInfo:      cString(dev.caps.BusInfo[:]),
+		Version:      fmt.Sprintf("%d.%d.%d", major, minor, patch),
+		Capabilities: dev.nodeCaps(),
+		Format:       dev.format,
+		Width:        dev.width,
+		Height:       dev.height,
+		BytesPerLine: dev.BytesPerLine(),
+		ImageSize:    dev.ImageSize(),
+		Field:        dev.pix.Field,
+		Buffers:      dev.buffers,
+		Memory:       memory,
+	}
+}
@@ -0,0 +1,172 @@
+package v4l
+
+import (
+	"fmt"
+)
+
+// Colorspace is the V4L2_COLORSPACE of a format: its primaries and white
+// point, and the defaults of the other colour parameters.
+type Colorspace uint32
+
+// XferFunc is the V4L2_XFER_FUNC transfer function frames are encoded
+// with, which has to be undone to get linear light.
+type XferFunc uint32
+
+// YCbCrEncoding is the V4L2_YCBCR_ENC matrix between R'G'B' and Y'CbCr.
+type YCbCrEncoding uint32
+
+// Quantization is the V4L2_QUANTIZATION range the values use.
+type Quantization uint32
+
+const (
+	V4L2_COLORSPACE_DEFAULT       Colorspace = 0
+	V4L2_COLORSPACE_SMPTE170M     Colorspace = 1
+	V4L2_COLORSPACE_SMPTE240M     Colorspace = 2
+	V4L2_COLORSPACE_REC709        Colorspace = 3
+	V4L2_COLORSPACE_BT878         Colorspace = 4
+	V4L2_COLORSPACE_470_SYSTEM_M  Colorspace = 5
+	V4L2_COLORSPACE_470_SYSTEM_BG Colorspace = 6
+	V4L2_COLORSPACE_JPEG          Colorspace = 7
+	V4L2_COLORSPACE_SRGB          Colorspace = 8
+	V4L2_COLORSPACE_OPRGB         Colorspace = 9
+	V4L2_COLORSPACE_BT2020        Colorspace = 10
+	V4L2_COLORSPACE_RAW           Colorspace = 11
+	V4L2_COLORSPACE_DCI_P3        Colorspace = 12
+
+	V4L2_XFER_FUNC_DEFAULT   XferFunc = 0
+	V4L2_XFER_FUNC_709       XferFunc = 1
+	V4L2_XFER_FUNC_SRGB      XferFunc = 2
+	V4L2_XFER_FUNC_OPRGB     XferFunc = 3
+	V4L2_XFER_FUNC_SMPTE240M XferFunc = 4
+	V4L2_XFER_FUNC_NONE      XferFunc = 5
+	V4L2_XFER_FUNC_DCI_P3    XferFunc = 6
+	V4L2_XFER_FUNC_SMPTE2084 XferFunc = 7
+
+	V4L2_YCBCR_ENC_DEFAULT          YCbCrEncoding = 0
+	V4L2_YCBCR_ENC_601              YCbCrEncoding = 1
+	V4L2_YCBCR_ENC_709              YCbCrEncoding = 2
+	V4L2_YCBCR_ENC_XV601            YCbCrEncoding = 3
+	V4L2_YCBCR_ENC_XV709            YCbCrEncoding = 4
+	V4L2_YCBCR_ENC_SYCC             YCbCrEncoding = 5
+	V4L2_YCBCR_ENC_BT2020           YCbCrEncoding = 6
+	V4L2_YCBCR_ENC_BT2020_CONST_LUM YCbCrEncoding = 7
+	V4L2_YCBCR_ENC_SMPTE240M        YCbCrEncoding = 8
+
+	V4L2_QUANTIZATION_DEFAULT    Quantization = 0
+	V4L2_QUANTIZATION_FULL_RANGE Quantization = 1
+	V4L2_QUANTIZATION_LIM_RANGE  Quantization = 2
+)
+
+var colorspaceNames = []string{"default", "SMPTE 170M", "SMPTE 240M", "Rec. 709", "BT.878",
+	"470 System M", "470 System BG", "JPEG", "sRGB", "opRGB", "BT.2020", "raw", "DCI-P3"}
+
+var xferFuncNames = []string{"default", "Rec. 709", "sRGB", "opRGB", "SMPTE 240M", "none",
+	"DCI-P3", "SMPTE 2084"}
+
+var ycbcrEncodingNames = []string{"default", "BT.601", "Rec. 709", "xvYCC 601", "xvYCC 709",
+	"sYCC", "BT.2020", "BT.2020 constant luminance", "SMPTE 240M"}
+
+var quantizationNames = []string{"default", "full range", "limited range"}
+
+func enumName(names []string, v uint32) string {
+
+	if int(v) < len(names) {
+		return names[v]
+	}
+
+	return fmt.Sprintf("unknown (%d)", v)
+}
+
+func (c Colorspace) String() string    { return enumName(colorspaceNames, uint32(c)) }
+func (x XferFunc) String() string      { return enumName(xferFuncNames, uint32(x)) }
+func (e YCbCrEncoding) String() string { return enumName(ycbcrEncodingNames, uint32(e)) }
+func (q Quantization) String() string  { return enumName(quantizationNames, uint32(q)) }
+
+// ColorInfo is the colour encoding of the frames a device captures.
+type ColorInfo struct {
+	Colorspace    Colorspace
+	XferFunc      XferFunc
+	YCbCrEncoding YCbCrEncoding
+	Quantization  Quantization
+}
+
+// ColorInfo reads the colour encoding of the current format from the
+// driver. Drivers commonly leave the transfer function, encoding and
+// quantization at their defaults, meaning whatever the colorspace implies;
+// those are resolved here the way the kernel's V4L2_MAP_*_DEFAULT macros
+// do, so that every field names a concrete value.
+func (dev *Device) ColorInfo() (ColorInfo, error) {
+
+	pix, err := dev.getFormat()
+	if err != nil {
+		return ColorInfo{}, fmt.Errorf("Failed to get format: %v", err.Error())
+	}
+
+	c := ColorInfo{
+		Colorspace:    Colorspace(pix.Colorspace),
+		XferFunc:      XferFunc(pix.XferFunc),
+		YCbCrEncoding: YCbCrEncoding(pix.YCBCREnc),
+		Quantization:  Quantization(pix.Quantization),
+	}
+
+	return c.resolve(rgbFormat(Format(pix.Pixelformat))), nil
+}
+
+// resolve replaces default values with what the colorspace implies. rgb
+// says whether the pixel format holds RGB rather than Y'CbCr samples,
+// which are full range by default.
+func (c ColorInfo) resolve(rgb bool) ColorInfo {
+
+	cs := c.Colorspace
+
+	if c.XferFunc == V4L2_XFER_FUNC_DEFAULT {
+		switch cs {
+		case V4L2_COLORSPACE_OPRGB:
+			c.XferFunc = V4L2_XFER_FUNC_OPRGB
+		case V4L2_COLORSPACE_SMPTE240M:
+			c.XferFunc = V4L2_XFER_FUNC_SMPTE240M
+		case V4L2_COLORSPACE_DCI_P3:
+			c.XferFunc = V4L2_XFER_FUNC_DCI_P3
+		case V4L2_COLORSPACE_RAW:
+			c.XferFunc = V4L2_XFER_FUNC_NONE
+		case V4L2_COLORSPACE_SRGB, V4L2_COLORSPACE_JPEG:
+			c.XferFunc = V4L2_XFER_FUNC_SRGB
+		default:
+			c.XferFunc = V4L2_XFER_FUNC_709
+		}
+	}
+
+	if c.YCbCrEncoding == V4L2_YCBCR_ENC_DEFAULT {
+		switch cs {
+		case V4L2_COLORSPACE_REC709, V4L2_COLORSPACE_DCI_P3:
+			c.YCbCrEncoding = V4L2_YCBCR_ENC_709
+		case V4L2_COLORSPACE_BT2020:
+			c.YCbCrEncoding = V4L2_YCBCR_ENC_BT2020
+		case V4L2_COLORSPACE_SMPTE240M:
+			c.YCbCrEncoding = V4L2_YCBCR_ENC_SMPTE240M
+		default:
+			c.YCbCrEncoding = V4L2_YCBCR_ENC_601
+		}
+	}
+
+	if c.Quantization == V4L2_QUANTIZATION_DEFAULT {
+		if rgb || cs == V4L2_COLORSPACE_JPEG {
+			c.Quantization = V4L2_QUANTIZATION_FULL_RANGE
+		} else {
+			c.Quantization = V4L2_QUANTIZATION_LIM_RANGE
+		}
+	}
+
+	return c
+}
+
+// rgbFormat reports whether format holds RGB samples, including raw Bayer
+// ones.
+func rgbFormat(format Format) bool {
+
+	if _, ok := bayer10Pattern(format); ok {
+		return true
+	}
+
+	return format == V4L2_PIX_FMT_RGBA32 || format == V4L2_PIX_FMT_RGB32
+}
@@ -0,0 +1,480 @@
+package v4l
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"syscall"
+)
+
+var ErrUnsupportedControl = errors.New("Unsupported control")
+
+const (
+	V4L2_CID_BASE                      uint32 = 0x00980900
+	V4L2_CID_BRIGHTNESS                       = V4L2_CID_BASE + 0
+	V4L2_CID_CONTRAST                         = V4L2_CID_BASE + 1
+	V4L2_CID_SATURATION                       = V4L2_CID_BASE + 2
+	V4L2_CID_HUE                              = V4L2_CID_BASE + 3
+	V4L2_CID_AUTO_WHITE_BALANCE               = V4L2_CID_BASE + 12
+	V4L2_CID_RED_BALANCE                      = V4L2_CID_BASE + 14
+	V4L2_CID_BLUE_BALANCE                     = V4L2_CID_BASE + 15
+	V4L2_CID_GAMMA                            = V4L2_CID_BASE + 16
+	V4L2_CID_EXPOSURE                         = V4L2_CID_BASE + 17
+	V4L2_CID_AUTOGAIN                         = V4L2_CID_BASE + 18
+	V4L2_CID_GAIN                             = V4L2_CID_BASE + 19
+	V4L2_CID_HFLIP                            = V4L2_CID_BASE + 20
+	V4L2_CID_VFLIP                            = V4L2_CID_BASE + 21
+	V4L2_CID_POWER_LINE_FREQUENCY             = V4L2_CID_BASE + 24
+	V4L2_CID_HUE_AUTO                         = V4L2_CID_BASE + 25
+	V4L2_CID_WHITE_BALANCE_TEMPERATURE        = V4L2_CID_BASE + 26
+	V4L2_CID_SHARPNESS                        = V4L2_CID_BASE + 27
+	V4L2_CID_BACKLIGHT_COMPENSATION           = V4L2_CID_BASE + 28
+	V4L2_CID_COLORFX                          = V4L2_CID_BASE + 31
+
+	V4L2_CID_CAMERA_CLASS_BASE uint32 = 0x009a0900
+	V4L2_CID_EXPOSURE_AUTO            = V4L2_CID_CAMERA_CLASS_BASE + 1
+	V4L2_CID_FOCUS_AUTO               = V4L2_CID_CAMERA_CLASS_BASE + 12
+
+	V4L2_CTRL_TYPE_INTEGER      uint32 = 1
+	V4L2_CTRL_TYPE_BOOLEAN             = 2
+	V4L2_CTRL_TYPE_MENU                = 3
+	V4L2_CTRL_TYPE_BUTTON              = 4
+	V4L2_CTRL_TYPE_INTEGER64           = 5
+	V4L2_CTRL_TYPE_CTRL_CLASS          = 6
+	V4L2_CTRL_TYPE_STRING              = 7
+	V4L2_CTRL_TYPE_BITMASK             = 8
+	V4L2_CTRL_TYPE_INTEGER_MENU        = 9
+
+	V4L2_CTRL_FLAG_DISABLED   uint32 = 0x0001
+	V4L2_CTRL_FLAG_GRABBED           = 0x0002
+	V4L2_CTRL_FLAG_READ_ONLY         = 0x0004
+	V4L2_CTRL_FLAG_UPDATE            = 0x0008
+	V4L2_CTRL_FLAG_INACTIVE          = 0x0010
+	V4L2_CTRL_FLAG_SLIDER            = 0x0020
+	V4L2_CTRL_FLAG_WRITE_ONLY        = 0x0040
+	V4L2_CTRL_FLAG_VOLATILE          = 0x0080
+	V4L2_CTRL_FLAG_NEXT_CTRL         = 0x80000000
+
+	VIDIOC_G_CTRL    uintptr = 0xC008561B
+	VIDIOC_S_CTRL            = 0xC008561C
+	VIDIOC_QUERYCTRL         = 0xC0445624
+	VIDIOC_QUERYMENU         = 0xC02C5625
+)
+
+type v4l2_control struct {
+	Id    uint32
+	Value int32
+}
+
+type v4l2_queryctrl struct {
+	Id, Type                             uint32
+	Name                                 [32]uint8
+	Minimum, Maximum, Step, DefaultValue int32
+	Flags                                uint32
+	Reserved                             [2]uint32
+}
+
+// v4l2_querymenu with the name member of its union; the kernel packs the
+// struct, so the 64-bit value of integer menus does not pad it.
+type v4l2_querymenu struct {
+	Id, Index uint32
+	Name      [32]uint8
+	Reserved  uint32
+}
+
+// ControlInfo describes a control as reported by QUERYCTRL.
+type ControlInfo struct {
+	ID                      uint32
+	Name                    string
+	Type                    uint32
+	Min, Max, Step, Default int32
+	Flags                   uint32
+}
+
+func (c ControlInfo) ReadOnly() bool {
+	return c.Flags&V4L2_CTRL_FLAG_READ_ONLY != 0
+}
+
+func (c ControlInfo) Inactive() bool {
+	return c.Flags&V4L2_CTRL_FLAG_INACTIVE != 0
+}
+
+func (dev *handle) GetControl(id uint32) (int32, error) {
+
+	b := toBytes(v4l2_control{Id: id})
+
+	if err := dev.ioctl(VIDIOC_G_CTRL, toUintptr(b)); err != nil {
+		return 0, controlError(err)
+	}
+
+	var c v4l2_control
+	if err := fromBytes(b, &c); err != nil {
+		return 0, err
+	}
+
+	return c.Value, nil
+}
+
+func (dev *Device) SetControl(id uint32, value int32) error {
+
+	b := toBytes(v4l2_control{Id: id, Value: value})
+
+	if err := dev.ioctl(VIDIOC_S_CTRL, toUintptr(b)); err != nil {
+		return controlError(err)
+	}
+
+	return nil
+}
+
+// TrySetControl sets a control the device may not have, returning false
+// rather than ErrUnsupportedControl when it does not.
+func (dev *Device) TrySetControl(id uint32, value int32) (bool, error) {
+
+	err := dev.SetControl(id, value)
+	if err == ErrUnsupportedControl {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// setMenuControl sets a menu control after checking with QUERYMENU that
+// the device offers the item, since drivers may leave holes in the range
+// and reject them with an error that reads like a missing control.
+func (dev *Device) setMenuControl(id uint32, value int32) error {
+
+	c, err := dev.ControlInfo(id)
+	if err != nil {
+		return err
+	}
+
+	if c.Type != V4L2_CTRL_TYPE_MENU {
+		return fmt.Errorf("%s is not a menu control", c.Name)
+	}
+
+	if value < c.Min || value > c.Max || !dev.menuItem(id, value) {
+		return fmt.Errorf("%s has no item %d", c.Name, value)
+	}
+
+	return dev.SetControl(id, value)
+}
+
+// MenuItem is an item of a menu control as reported by QUERYMENU.
+type MenuItem struct {
+	Index int32
+	Name  string
+}
+
+// MenuItems lists the items the device offers for menu control id, which
+// may skip indices within the control's range.
+func (dev *handle) MenuItems(id uint32) ([]MenuItem, error) {
+
+	c, err := dev.ControlInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Type != V4L2_CTRL_TYPE_MENU {
+		return nil, fmt.Errorf("%s is not a menu control", c.Name)
+	}
+
+	var items []MenuItem
+
+	for i := c.Min; i <= c.Max; i++ {
+
+		q := v4l2_querymenu{Id: id, Index: uint32(i)}
+		b := toBytes(q)
+
+		if err := dev.ioctl(VIDIOC_QUERYMENU, toUintptr(b)); err != nil {
+			continue
+		}
+
+		if err := fromBytes(b, &q); err != nil {
+			return nil, err
+		}
+
+		items = append(items, MenuItem{Index: i, Name: cString(q.Name[:])})
+	}
+
+	return items, nil
+}
+
+// menuItem reports whether the device offers item index of menu control id.
+func (dev *handle) menuItem(id uint32, index int32) bool {
+
+	b := toBytes(v4l2_querymenu{Id: id, Index: uint32(index)})
+
+	return dev.ioctl(VIDIOC_QUERYMENU, toUintptr(b)) == nil
+}
+
+// ControlExists reports whether the device has an enabled control id. It
+// only queries the control, so it has no side effects.
+func (dev *handle) ControlExists(id uint32) bool {
+	_, err := dev.ControlInfo(id)
+	return err == nil
+}
+
+func (dev *handle) ControlInfo(id uint32) (ControlInfo, error) {
+
+	q, err := dev.queryControl(id)
+	if err != nil {
+		return ControlInfo{}, err
+	}
+
+	if q.Flags&V4L2_CTRL_FLAG_DISABLED != 0 {
+		return ControlInfo{}, ErrUnsupportedControl
+	}
+
+	return q.info(), nil
+}
+
+// Controls enumerates every control the device reports.
+func (dev *handle) Controls() ([]ControlInfo, error) {
+
+	var controls []ControlInfo
+
+	id := uint32(V4L2_CTRL_FLAG_NEXT_CTRL)
+	for {
+
+		q, err := dev.queryControl(id)
+		if err == ErrUnsupportedControl {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if q.Flags&V4L2_CTRL_FLAG_DISABLED == 0 {
+			controls = append(controls, q.info())
+		}
+
+		id = q.Id | V4L2_CTRL_FLAG_NEXT_CTRL
+	}
+
+	return controls, nil
+}
+
+// autoControls gate other controls of their cluster: while one is enabled
+// its manual counterparts are inactive. They are written before anything
+// else so the state of the dependent controls is known.
+var autoControls = []uint32{
+	V4L2_CID_EXPOSURE_AUTO,
+	V4L2_CID_AUTOGAIN,
+	V4L2_CID_AUTO_WHITE_BALANCE,
+	V4L2_CID_HUE_AUTO,
+	V4L2_CID_FOCUS_AUTO,
+}
+
+// ResetControls writes every control back to its default value. The auto
+// controls of each cluster go first; afterwards controls are re-queried and
+// any that are read-only, write-only (the relative and button style
+// controls, where writing moves the hardware), inactive because their auto
+// control is on, or not settable through S_CTRL (64-bit, string and class
+// controls) are skipped.
+func (dev *Device) ResetControls() error {
+
+	for _, id := range autoControls {
+
+		c, err := dev.ControlInfo(id)
+		if err == ErrUnsupportedControl {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !resettable(c) {
+			continue
+		}
+
+		if err := dev.SetControl(id, c.Default); err != nil {
+			return fmt.Errorf("Failed to reset %s: %v", c.Name, err.Error())
+		}
+	}
+
+	controls, err := dev.Controls()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range controls {
+
+		if isAutoControl(c.ID) || !resettable(c) {
+			continue
+		}
+
+		if err := dev.SetControl(c.ID, c.Default); err != nil {
+			return fmt.Errorf("Failed to reset %s: %v", c.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func isAutoControl(id uint32) bool {
+
+	for _, a := range autoControls {
+		if a == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resettable(c ControlInfo) bool {
+
+	if c.Flags&(V4L2_CTRL_FLAG_READ_ONLY|V4L2_CTRL_FLAG_WRITE_ONLY|V4L2_CTRL_FLAG_INACTIVE) != 0 {
+		return false
+	}
+
+	return plainControl(c.Type)
+}
+
+// plainControl reports whether controls of type t hold a 32-bit value that
+// G_CTRL and S_CTRL can transfer.
+func plainControl(t uint32) bool {
+
+	switch t {
+	case V4L2_CTRL_TYPE_INTEGER, V4L2_CTRL_TYPE_BOOLEAN, V4L2_CTRL_TYPE_MENU,
+		V4L2_CTRL_TYPE_INTEGER_MENU, V4L2_CTRL_TYPE_BITMASK:
+		return true
+	}
+
+	return false
+}
+
+// ControlState maps control ids to values as captured by SaveControlState.
+type ControlState map[uint32]int32
+
+// SaveControlState reads the value of every control that RestoreControlState
+// could later write back.
+func (dev *Device) SaveControlState() (ControlState, error) {
+
+	controls, err := dev.Controls()
+	if err != nil {
+		return nil, err
+	}
+
+	state := ControlState{}
+
+	for _, c := range controls {
+
+		if c.Flags&(V4L2_CTRL_FLAG_READ_ONLY|V4L2_CTRL_FLAG_WRITE_ONLY) != 0 || !plainControl(c.Type) {
+			continue
+		}
+
+		v, err := dev.GetControl(c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s: %v", c.Name, err.Error())
+		}
+
+		state[c.ID] = v
+	}
+
+	return state, nil
+}
+
+// RestoreControlState writes back a saved state, auto controls first so the
+// manual controls they gate are writable. Controls the device no longer has
+// or that are inactive under the restored auto settings are skipped.
+func (dev *Device) RestoreControlState(state ControlState) error {
+
+	restore := func(id uint32, v int32) error {
+
+		c, err := dev.ControlInfo(id)
+		if err == ErrUnsupportedControl {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !resettable(c) {
+			return nil
+		}
+
+		if err := dev.SetControl(id, v); err != nil && err != ErrUnsupportedControl {
+			return fmt.Errorf("Failed to restore %s: %v", c.Name, err.Error())
+		}
+
+		return nil
+	}
+
+	for _, id := range autoControls {
+		if v, ok := state[id]; ok {
+			if err := restore(id, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	ids := make([]uint32, 0, len(state))
+	for id := range state {
+		if !isAutoControl(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		if err := restore(id, state[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dev *handle) queryControl(id uint32) (v4l2_queryctrl, error) {
+
+	q := v4l2_queryctrl{Id: id}
+
+	b := toBytes(q)
+
+	if err := dev.ioctl(VIDIOC_QUERYCTRL, toUintptr(b)); err != nil {
+		return q, controlError(err)
+	}
+
+	if err := fromBytes(b, &q); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func (q v4l2_queryctrl) info() ControlInfo {
+	return ControlInfo{
+		ID:      q.Id,
+		Name:    cString(q.Name[:]),
+		Type:    q.Type,
+		Min:     q.Minimum,
+		Max:     q.Maximum,
+		Step:    q.Step,
+		Default: q.DefaultValue,
+		Flags:   q.Flags,
+	}
+}
+
+// The control ioctls report an unknown id as EINVAL.
+func controlError(err error) error {
+
+	if errors.Is(err, syscall.EINVAL) {
+		return ErrUnsupportedControl
+	}
+
+	return err
+}
+
+func cString(b []byte) string {
+
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,239 @@
+package v4l
+
+import (
+	"image"
+	"sync"
+)
+
+// Converter turns one frame of a pixel format into RGBA. src holds height
+// lines of width pixels, stride bytes apart. dst is the frame downscaled
+// by scale, the device's SetScale factor, sampling every scale-th pixel of
+// every scale-th line; it may cover only a band of the lines when frames
+// are converted in parallel.
+type Converter func(src []byte, width, height, stride, scale int, dst *image.RGBA)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[Format]Converter{
+		V4L2_PIX_FMT_YUYV:     packed422Converter(packed422Layouts[V4L2_PIX_FMT_YUYV]),
+		V4L2_PIX_FMT_UYVY:     packed422Converter(packed422Layouts[V4L2_PIX_FMT_UYVY]),
+		V4L2_PIX_FMT_YVYU:     packed422Converter(packed422Layouts[V4L2_PIX_FMT_YVYU]),
+		V4L2_PIX_FMT_VYUY:     packed422Converter(packed422Layouts[V4L2_PIX_FMT_VYUY]),
+		V4L2_PIX_FMT_NV12:     nv12Converter(false),
+		V4L2_PIX_FMT_NV21:     nv12Converter(true),
+		V4L2_PIX_FMT_RGBA32:   convertRGBA,
+		V4L2_PIX_FMT_RGB24:    packedRGBConverter(3, 0, 1, 2),
+		V4L2_PIX_FMT_BGR24:    packedRGBConverter(3, 2, 1, 0),
+		V4L2_PIX_FMT_RGB32:    packedRGBConverter(4, 1, 2, 3),
+		V4L2_PIX_FMT_Y210:     convertY210,
+		V4L2_PIX_FMT_Y16:      convertY16,
+		V4L2_PIX_FMT_SRGGB10P: bayer10Converter(RGGB),
+		V4L2_PIX_FMT_SGRBG10P: bayer10Converter(GRBG),
+		V4L2_PIX_FMT_SGBRG10P: bayer10Converter(GBRG),
+		V4L2_PIX_FMT_SBGGR10P: bayer10Converter(BGGR),
+		V4L2_PIX_FMT_MJPEG:    convertMJPEG,
+	}
+
+	// lutConverters build, for the formats whose shipped converter can map
+	// samples through a SetLUT table as it writes them, that converter
+	// with the table fused in, which saves a second pass over the image.
+	lutConverters = map[Format]func(lut *[256]uint8) Converter{
+		V4L2_PIX_FMT_YUYV: packed422LUTConverter(packed422Layouts[V4L2_PIX_FMT_YUYV]),
+		V4L2_PIX_FMT_UYVY: packed422LUTConverter(packed422Layouts[V4L2_PIX_FMT_UYVY]),
+		V4L2_PIX_FMT_YVYU: packed422LUTConverter(packed422Layouts[V4L2_PIX_FMT_YVYU]),
+		V4L2_PIX_FMT_VYUY: packed422LUTConverter(packed422Layouts[V4L2_PIX_FMT_VYUY]),
+		V4L2_PIX_FMT_NV12: nv12LUTConverter(false),
+		V4L2_PIX_FMT_NV21: nv12LUTConverter(true),
+	}
+)
+
+// identityLUT maps every sample to itself, for conversions without a LUT.
+var identityLUT = func() *[256]uint8 {
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(i)
+	}
+
+	return &lut
+}()
+
+// RegisterConverter makes GetFrame able to convert format, replacing any
+// converter the package ships for it. Devices opened in a format without a
+// converter can only be read with GetRawFrame.
+func RegisterConverter(format Format, c Converter) {
+
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+
+	converters[format] = c
+
+	// A LUT is applied after the replacement's conversion instead.
+	delete(lutConverters, format)
+}
+
+func lookupConverter(format Format) Converter {
+
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	return converters[format]
+}
+
+// lookupLUTConverter returns the converter for format with lut fused in,
+// or nil if the format's converter does not take one.
+func lookupLUTConverter(format Format, lut *[256]uint8) Converter {
+
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	if build := lutConverters[format]; build != nil {
+		return build(lut)
+	}
+
+	return nil
+}
+
+// sampleStep is the downscale factor to sample a source of width x height
+// into dst at: scale, unless that would sample past either edge, when it
+// is the largest factor that fits.
+func sampleStep(width, height, scale int, dst *image.RGBA) int {
+
+	b := dst.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return 1
+	}
+
+	if scale >= 1 && b.Dx()*scale <= width && b.Dy()*scale <= height {
+		return scale
+	}
+
+	step := width / b.Dx()
+	if s := height / b.Dy(); s < step {
+		step = s
+	}
+
+	if step < 1 {
+		step = 1
+	}
+
+	return step
+}
+
+// padFrame returns src extended with zeros to at least n bytes, so a short
+// frame from the driver converts to a partly black image rather than
+// indexing out of range.
+func padFrame(src []byte, n int) []byte {
+
+	if len(src) >= n {
+		return src
+	}
+
+	b := make([]byte, n)
+	copy(b, src)
+
+	return b
+}
+
+// unpadFrame drops the padding a driver adds after each line of height
+// lines of line bytes placed stride apart, for the converters that expect
+// lines back to back.
+func unpadFrame(src []byte, line, stride, height int) []byte {
+
+	if stride <= line {
+		return src
+	}
+
+	b := make([]byte, line*height)
+	for y := 0; y < height && y*stride < len(src); y++ {
+		copy(b[y*line:(y+1)*line], src[y*stride:])
+	}
+
+	return b
+}
+
+// The sizes below leave room for a trailing half-filled pixel pair or
+// Bayer block when the width or height is odd.
+
+func packed422Converter(l packed422) Converter {
+	return packed422LUTConverter(l)(identityLUT)
+}
+
+func packed422LUTConverter(l packed422) func(*[256]uint8) Converter {
+	return func(lut *[256]uint8) Converter {
+		return func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+
+			src = padFrame(unpadFrame(src, width*2, stride, height), width*2*height+4)
+
+			if step := sampleStep(width, height, scale, dst); step > 1 {
+				frameToScaledImage(src, width, step, l, lut, dst)
+				return
+			}
+
+			frameToImage(src, l, lut, dst)
+		}
+	}
+}
+
+func nv12Converter(swapped bool) Converter {
+	return nv12LUTConverter(swapped)(identityLUT)
+}
+
+func nv12LUTConverter(swapped bool) func(*[256]uint8) Converter {
+	return func(lut *[256]uint8) Converter {
+		return func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+			src = padFrame(src, stride*(height+(height+1)/2)+2)
+			nv12ToImage(src, stride, height, sampleStep(width, height, scale, dst), swapped, lut, dst)
+		}
+	}
+}
+
+func convertRGBA(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+	src = padFrame(unpadFrame(src, width*4, stride, height), width*4*height)
+	rgbaToImage(src, width, sampleStep(width, height, scale, dst), dst)
+}
+
+// packedRGBConverter converts frames of size byte pixels holding red,
+// green and blue at byte offsets r, g and b, such as RGB24 and the
+// alpha-first RGB32. Any other byte of a pixel is ignored and the image is
+// left opaque.
+func packedRGBConverter(size, r, g, b int) Converter {
+	return func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+
+		if line := width * size; stride < line {
+			stride = line
+		}
+
+		src = padFrame(src, stride*height)
+		step := sampleStep(width, height, scale, dst)
+		bounds := dst.Bounds()
+
+		for y := 0; y < bounds.Dy(); y++ {
+
+			row := src[y*step*stride:]
+			out := dst.Pix[y*dst.Stride:]
+
+			for x := 0; x < bounds.Dx(); x++ {
+				p := x * step * size
+				out[x*4+0], out[x*4+1], out[x*4+2], out[x*4+3] = row[p+r], row[p+g], row[p+b], 0xff
+			}
+		}
+	}
+}
+
+func convertY210(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+	src = padFrame(unpadFrame(src, width*4, stride, height), width*4*height+8)
+	y210ToImage(src, width, sampleStep(width, height, scale, dst), dst)
+}
+
+func convertY16(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+	src = padFrame(unpadFrame(src, width*2, stride, height), width*2*height)
+	y16ToImage(src, width, sampleStep(width, height, scale, dst), dst)
+}
+
+func bayer10Converter(pattern BayerPattern) Converter {
+	return func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+		src = padFrame(src, stride*(height+1)+5)
+		bayer10ToImage(src, width, height, stride, sampleStep(width, height, scale, dst), pattern, dst)
+	}
+}
@@ -0,0 +1,149 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"sort"
+	"testing"
+)
+
+// shippedFormats returns the formats with a registered converter, in a
+// fixed order.
+func shippedFormats() []Format {
+
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	var formats []Format
+	for f := range converters {
+		formats = append(formats, f)
+	}
+
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+
+	return formats
+}
+
+// FuzzFrameToImage feeds the converters frames of any length for any
+// format, size and line padding, as a misbehaving driver might deliver
+// them, and fails if one panics.
+func FuzzFrameToImage(f *testing.F) {
+
+	f.Add(uint8(0), uint8(8), uint8(4), uint8(0), make([]byte, 8*4*2))
+	f.Add(uint8(4), uint8(7), uint8(5), uint8(3), make([]byte, 10))
+	f.Add(uint8(12), uint8(5), uint8(3), uint8(1), []byte{})
+
+	formats := shippedFormats()
+
+	f.Fuzz(func(t *testing.T, format, width, height, pad uint8, frame []byte) {
+
+		pf := formats[int(format)%len(formats)]
+		w, h := int(width)%128+1, int(height)%128+1
+		stride := (&Device{format: pf, width: w}).stride() + int(pad)
+
+		im := image.NewRGBA(image.Rect(0, 0, w, h))
+		lookupConverter(pf)(frame, w, h, stride, 1, im)
+	})
+}
+
+func TestConvertersOpaque(t *testing.T) {
+
+	const width, height = 16, 8
+
+	for _, format := range shippedFormats() {
+
+		// Compressed frames of random bytes do not decode.
+		if format.Compressed() {
+			continue
+		}
+
+		dev := &Device{format: format, width: width}
+		frame := make([]byte, dev.stride()*height*2)
+		for i := range frame {
+			frame[i] = uint8(i * 13)
+		}
+
+		for _, scale := range []int{1, 2} {
+
+			im := image.NewRGBA(image.Rect(0, 0, width/scale, height/scale))
+			lookupConverter(format)(frame, width, height, dev.stride(), scale, im)
+
+			for i := 3; i < len(im.Pix); i += 4 {
+				if im.Pix[i] != 0xff {
+					t.Errorf("%v at scale %d: alpha of pixel %d is %d", format, scale, i/4, im.Pix[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+// nv12Frame builds a width x height NV12 frame with lines stride bytes
+// apart, the padding filled with a value no sample has.
+func nv12Frame(width, height, stride int) []byte {
+
+	ch := (height + 1) / 2
+	frame := bytes.Repeat([]byte{0xee}, stride*(height+ch))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			frame[y*stride+x] = uint8(16 + (x*7+y*13)%200)
+		}
+	}
+
+	for y := 0; y < ch; y++ {
+		for x := 0; x < (width+1)/2; x++ {
+			c := stride*height + y*stride + x*2
+			frame[c], frame[c+1] = uint8(64+x*5%128), uint8(160-y*3%96)
+		}
+	}
+
+	return frame
+}
+
+func TestNV12Stride(t *testing.T) {
+
+	const width, height = 10, 6
+
+	packed := image.NewRGBA(image.Rect(0, 0, width, height))
+	lookupConverter(V4L2_PIX_FMT_NV12)(nv12Frame(width, height, width), width, height, width, 1, packed)
+
+	for _, stride := range []int{width, 16, 32} {
+
+		im := image.NewRGBA(image.Rect(0, 0, width, height))
+		lookupConverter(V4L2_PIX_FMT_NV12)(nv12Frame(width, height, stride), width, height, stride, 1, im)
+
+		if !bytes.Equal(im.Pix, packed.Pix) {
+			t.Errorf("stride %d: image differs from the packed frame's", stride)
+		}
+	}
+
+	if got, want := ImageSizeFor(V4L2_PIX_FMT_NV12, width, height, 16), 16*(height+height/2); got != want {
+		t.Errorf("ImageSizeFor = %d, want %d", got, want)
+	}
+}
+
+func TestRGBA32Opaque(t *testing.T) {
+
+	const width, height = 8, 4
+
+	// A driver leaving the alpha bytes zero.
+	src := make([]byte, width*4*height)
+	for i := range src {
+		if i%4 != 3 {
+			src[i] = uint8(i)
+		}
+	}
+
+	for _, size := range []image.Rectangle{image.Rect(0, 0, width, height), image.Rect(0, 0, width/2, height/2)} {
+
+		im := image.NewRGBA(size)
+		lookupConverter(V4L2_PIX_FMT_RGBA32)(src, width, height, width*4, 1, im)
+
+		for i := 3; i < len(im.Pix); i += 4 {
+			if im.Pix[i] != 0xff {
+				t.Fatalf("%v: alpha of pixel %d = %d, want 255", size, i/4, im.Pix[i])
+			}
+		}
+	}
+}
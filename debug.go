@@ -0,0 +1,74 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+const (
+	V4L2_CHIP_MATCH_BRIDGE uint32 = 0
+	V4L2_CHIP_MATCH_SUBDEV        = 4
+
+	VIDIOC_DBG_S_REGISTER uintptr = 0x4038564F
+	VIDIOC_DBG_G_REGISTER         = 0xC0385650
+)
+
+// v4l2_dbg_register with the addr member of the match union; the kernel
+// packs the struct.
+type v4l2_dbg_register struct {
+	MatchType, MatchAddr uint32
+	_                    [28]uint8
+	Size                 uint32
+	Reg, Val             uint64
+}
+
+// DbgReadRegister reads hardware register reg of a chip of the device:
+// with V4L2_CHIP_MATCH_BRIDGE and addr 0 the bridge itself, with
+// V4L2_CHIP_MATCH_SUBDEV the sub-device at index addr, such as the sensor.
+// It is meant for driver and sensor bring-up. The kernel must be built
+// with CONFIG_VIDEO_ADV_DEBUG, the driver must implement register access
+// and the caller needs CAP_SYS_ADMIN.
+func (dev *handle) DbgReadRegister(match, addr uint32, reg uint64) (uint64, error) {
+
+	r := v4l2_dbg_register{MatchType: match, MatchAddr: addr, Reg: reg}
+	b := toBytes(r)
+
+	if err := dev.ioctl(VIDIOC_DBG_G_REGISTER, toUintptr(b)); err != nil {
+		return 0, registerError(err)
+	}
+
+	if err := fromBytes(b, &r); err != nil {
+		return 0, err
+	}
+
+	return r.Val, nil
+}
+
+// DbgWriteRegister writes val to hardware register reg, as
+// DbgReadRegister describes. Writing registers behind the driver's back
+// can leave the device in a state the driver does not expect.
+func (dev *Device) DbgWriteRegister(match, addr uint32, reg, val uint64) error {
+
+	r := v4l2_dbg_register{MatchType: match, MatchAddr: addr, Reg: reg, Val: val}
+
+	if err := dev.ioctl(VIDIOC_DBG_S_REGISTER, toUintptr(toBytes(r))); err != nil {
+		return registerError(err)
+	}
+
+	return nil
+}
+
+// registerError explains the errors the register ioctls fail with when the
+// kernel or caller is not set up for them.
+func registerError(err error) error {
+
+	switch {
+	case errors.Is(err, syscall.ENOTTY):
+		return errors.New("Register access unsupported: the kernel needs CONFIG_VIDEO_ADV_DEBUG and a driver that implements it")
+	case errors.Is(err, syscall.EPERM):
+		return errors.New("Register access needs CAP_SYS_ADMIN")
+	}
+
+	return fmt.Errorf("Failed to access register: %w", err)
+}
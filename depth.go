@@ -0,0 +1,105 @@
+package v4l
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+var (
+	V4L2_PIX_FMT_Y16 = FourCC("Y16 ")
+)
+
+// UnpackY16 copies a frame of little endian 16-bit samples, the layout of
+// V4L2_PIX_FMT_Y16 used by depth and thermal cameras, into an image.Gray16
+// keeping the raw values for measurement.
+func UnpackY16(frame []byte, width, height, stride int) (*image.Gray16, error) {
+
+	if stride < width*2 || len(frame) < stride*(height-1)+width*2 {
+		return nil, fmt.Errorf("Frame too short for %dx%d: %d bytes", width, height, len(frame))
+	}
+
+	im := image.NewGray16(image.Rect(0, 0, width, height))
+
+	p := 0
+	for y := 0; y < height; y++ {
+		row := frame[y*stride:]
+		for x := 0; x < width; x++ {
+			binary.BigEndian.PutUint16(im.Pix[p:], binary.LittleEndian.Uint16(row[x*2:]))
+			p += 2
+		}
+	}
+
+	return im, nil
+}
+
+// GetFrameGray16 captures a frame from a V4L2_PIX_FMT_Y16 device and
+// returns its raw samples.
+func (dev *Device) GetFrameGray16() (*image.Gray16, error) {
+
+	if dev.format != V4L2_PIX_FMT_Y16 {
+		return nil, fmt.Errorf("Unsupported format for Gray16: %v", dev.format)
+	}
+
+	frame, _, err := dev.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	return UnpackY16(frame, dev.width, dev.height, dev.stride())
+}
+
+// DepthToGray8 maps the samples of im between min and max linearly onto
+// black to white for display. Samples outside the window are clipped to
+// it.
+func DepthToGray8(im *image.Gray16, min, max uint16) *image.Gray {
+
+	b := im.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	if max < min {
+		min, max = max, min
+	}
+
+	span := uint32(1)
+	if max > min {
+		span = uint32(max - min)
+	}
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+
+			v := im.Gray16At(b.Min.X+x, b.Min.Y+y).Y
+			if v < min {
+				v = min
+			}
+			if v > max {
+				v = max
+			}
+
+			out.Pix[y*out.Stride+x] = uint8(uint32(v-min) * 255 / span)
+		}
+	}
+
+	return out
+}
+
+// y16ToImage shows a Y16 frame as gray from the high byte of each sample.
+func y16ToImage(frame []byte, width, scale int, im *image.RGBA) {
+
+	stride := width * 2
+	b := im.Bounds()
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+
+		row := frame[y*scale*stride:]
+
+		for x := 0; x < b.Dx(); x++ {
+			v := row[x*scale*2+1]
+			im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = v, v, v, 0xff
+			p += 4
+		}
+	}
+
+}
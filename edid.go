@@ -0,0 +1,90 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+const (
+	VIDIOC_G_EDID uintptr = 0xC0285628
+	VIDIOC_S_EDID         = 0xC0285629
+)
+
+// edidBlockSize is the size of one EDID block.
+const edidBlockSize = 128
+
+type v4l2_edid struct {
+	Pad, StartBlock, Blocks uint32
+	Reserved                [5]uint32
+	Edid                    uint64
+}
+
+// ReadEDID reads the EDID an HDMI receiver advertises to the source on its
+// input pad. Most devices have a single pad, numbered 0.
+func (dev *handle) ReadEDID(pad int) ([]byte, error) {
+
+	// Asking for no blocks makes the driver report how many it has.
+	n, err := dev.edid(VIDIOC_G_EDID, pad, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get EDID size: %v", err.Error())
+	}
+
+	if n == 0 {
+		return nil, nil
+	}
+
+	data := make([]byte, n*edidBlockSize)
+
+	if _, err := dev.edid(VIDIOC_G_EDID, pad, data); err != nil {
+		return nil, fmt.Errorf("Failed to get EDID: %v", err.Error())
+	}
+
+	return data, nil
+}
+
+// WriteEDID replaces the EDID an HDMI receiver advertises on its input
+// pad, which makes the source pick its output mode anew. data must be a
+// whole number of 128 byte blocks; empty data clears the EDID, which most
+// sources take as the receiver being unplugged.
+func (dev *Device) WriteEDID(pad int, data []byte) error {
+
+	if len(data)%edidBlockSize != 0 {
+		return fmt.Errorf("EDID is not a whole number of blocks: %d bytes", len(data))
+	}
+
+	n, err := dev.edid(VIDIOC_S_EDID, pad, data)
+	if errors.Is(err, syscall.E2BIG) {
+		return fmt.Errorf("EDID too large: %d blocks, the device takes %d", len(data)/edidBlockSize, n)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to set EDID: %v", err.Error())
+	}
+
+	return nil
+}
+
+// edid issues a G_EDID or S_EDID request for the blocks in data and
+// returns the block count the driver left in the request.
+func (dev *handle) edid(req uintptr, pad int, data []byte) (int, error) {
+
+	e := v4l2_edid{
+		Pad:    uint32(pad),
+		Blocks: uint32(len(data) / edidBlockSize),
+	}
+	if len(data) > 0 {
+		e.Edid = uint64(toUintptr(data))
+	}
+
+	b := toBytes(e)
+
+	err := dev.ioctl(req, toUintptr(b))
+	runtime.KeepAlive(data)
+
+	if ferr := fromBytes(b, &e); ferr != nil && err == nil {
+		err = ferr
+	}
+
+	return int(e.Blocks), err
+}
@@ -0,0 +1,174 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// Encoder turns an RGBA image into one frame of a pixel format, the
+// inverse of a Converter, for writing to output devices such as
+// v4l2loopback. dst holds the image's lines stride bytes apart and is
+// large enough for all of them.
+type Encoder func(src *image.RGBA, dst []byte, stride int)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[Format]Encoder{
+		V4L2_PIX_FMT_YUYV:   encodeYUYV,
+		V4L2_PIX_FMT_RGBA32: packedRGBEncoder(4, 0, 1, 2, 3),
+		V4L2_PIX_FMT_RGB24:  packedRGBEncoder(3, 0, 1, 2, -1),
+		V4L2_PIX_FMT_BGR24:  packedRGBEncoder(3, 2, 1, 0, -1),
+		V4L2_PIX_FMT_RGB32:  packedRGBEncoder(4, 1, 2, 3, 0),
+	}
+)
+
+// RegisterEncoder makes EncodeFrame able to encode format, replacing any
+// encoder the package ships for it.
+func RegisterEncoder(format Format, e Encoder) {
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[format] = e
+}
+
+func lookupEncoder(format Format) Encoder {
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	return encoders[format]
+}
+
+// encodedLine is the size of a line of width pixels of format as
+// EncodeFrame packs it. Packed 4:2:2 lines of odd width carry a whole
+// trailing pixel pair.
+func encodedLine(format Format, width int) int {
+
+	if _, ok := packed422Layouts[format]; ok {
+		return ((width + 1) &^ 1) * 2
+	}
+
+	return packedLine(format, width)
+}
+
+// EncodeFrame converts im to a frame of format with its lines back to
+// back, using the encoder registered for it.
+func EncodeFrame(im *image.RGBA, format Format) ([]byte, error) {
+
+	e := lookupEncoder(format)
+	if e == nil {
+		return nil, fmt.Errorf("Unsupported format for encoding: %v", format)
+	}
+
+	b := im.Bounds()
+	line := encodedLine(format, b.Dx())
+	frame := make([]byte, line*b.Dy())
+
+	e(im, frame, line)
+
+	return frame, nil
+}
+
+// EncodeRGBAToYUYV converts im to a YUYV frame, the inverse of the YUYV
+// capture conversion, for writing to output devices such as v4l2loopback.
+// Each pair of pixels shares the mean of their chroma. Lines of odd width
+// are padded by repeating their last pixel.
+func EncodeRGBAToYUYV(im *image.RGBA) []byte {
+
+	frame, _ := EncodeFrame(im, V4L2_PIX_FMT_YUYV)
+
+	return frame
+}
+
+func encodeYUYV(im *image.RGBA, frame []byte, stride int) {
+
+	b := im.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+
+		row := im.Pix[im.PixOffset(b.Min.X, y):]
+		i := (y - b.Min.Y) * stride
+
+		for x := 0; x < b.Dx(); x += 2 {
+
+			p := x * 4
+			q := p
+			if x+1 < b.Dx() {
+				q += 4
+			}
+
+			y0, cb0, cr0 := color.RGBToYCbCr(row[p+0], row[p+1], row[p+2])
+			y1, cb1, cr1 := color.RGBToYCbCr(row[q+0], row[q+1], row[q+2])
+
+			frame[i+0] = y0
+			frame[i+1] = uint8((int(cb0) + int(cb1) + 1) / 2)
+			frame[i+2] = y1
+			frame[i+3] = uint8((int(cr0) + int(cr1) + 1) / 2)
+			i += 4
+		}
+	}
+}
+
+// packedRGBEncoder packs pixels of size bytes with red, green and blue at
+// byte offsets r, g and b, and alpha at a unless it is negative, in which
+// case the alpha is dropped.
+func packedRGBEncoder(size, r, g, b, a int) Encoder {
+	return func(im *image.RGBA, frame []byte, stride int) {
+
+		bounds := im.Bounds()
+
+		for y := 0; y < bounds.Dy(); y++ {
+
+			row := im.Pix[im.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			out := frame[y*stride:]
+
+			for x := 0; x < bounds.Dx(); x++ {
+				p, q := x*4, x*size
+				out[q+r], out[q+g], out[q+b] = row[p+0], row[p+1], row[p+2]
+				if a >= 0 {
+					out[q+a] = row[p+3]
+				}
+			}
+		}
+	}
+}
+
+// Encode converts im, which must have the device's size, into dst as a
+// frame of the device's format laid out with the driver's line stride,
+// ready to be queued with QueueDMABUF once dst's buffer is the one the
+// descriptor refers to. Only single-plane formats can be encoded.
+func (dev *OutputDevice) Encode(im *image.RGBA, dst []byte) error {
+
+	format := Format(dev.pix.Pixelformat)
+
+	e := lookupEncoder(format)
+	if e == nil {
+		return fmt.Errorf("Unsupported format for encoding: %v", format)
+	}
+
+	if dev.Planes() != 1 {
+		return fmt.Errorf("Cannot encode %d-plane format %v", dev.Planes(), format)
+	}
+
+	b := im.Bounds()
+	if b.Dx() != int(dev.pix.Width) || b.Dy() != int(dev.pix.Height) {
+		return fmt.Errorf("Image size %dx%d does not match the device %dx%d", b.Dx(), b.Dy(), dev.pix.Width, dev.pix.Height)
+	}
+
+	line := encodedLine(format, b.Dx())
+	stride := int(dev.pix.PlaneFmt[0].Bytesperline)
+	if stride < line {
+		stride = line
+	}
+
+	if len(dst) < (b.Dy()-1)*stride+line {
+		return fmt.Errorf("Buffer too small: %d < %d", len(dst), (b.Dy()-1)*stride+line)
+	}
+
+	e(im, dst, stride)
+
+	return nil
+}
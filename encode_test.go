@@ -0,0 +1,111 @@
+package v4l
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// near reports whether a and b differ by at most tolerance in every
+// channel.
+func near(a, b color.RGBA, tolerance int) bool {
+
+	for _, d := range [4]int{
+		int(a.R) - int(b.R), int(a.G) - int(b.G),
+		int(a.B) - int(b.B), int(a.A) - int(b.A),
+	} {
+		if d < -tolerance || d > tolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// flatImage returns a width x height image filled with c.
+func flatImage(width, height int, c color.RGBA) *image.RGBA {
+
+	im := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(im.Pix); i += 4 {
+		im.Pix[i+0], im.Pix[i+1], im.Pix[i+2], im.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+
+	return im
+}
+
+func TestEncodeYUYVRoundTrip(t *testing.T) {
+
+	for _, c := range []color.RGBA{
+		{0, 0, 0, 0xff},
+		{0xff, 0xff, 0xff, 0xff},
+		{0xff, 0, 0, 0xff},
+		{0, 0xff, 0, 0xff},
+		{0, 0, 0xff, 0xff},
+		{0x80, 0x40, 0xc0, 0xff},
+	} {
+		for _, width := range []int{8, 2} {
+
+			src := flatImage(width, 4, c)
+			frame := EncodeRGBAToYUYV(src)
+
+			im := image.NewRGBA(src.Rect)
+			lookupConverter(V4L2_PIX_FMT_YUYV)(frame, width, 4, width*2, 1, im)
+
+			for y := 0; y < 4; y++ {
+				for x := 0; x < width; x++ {
+					if got := im.RGBAAt(x, y); !near(got, c, 2) {
+						t.Fatalf("%v width %d: pixel %d,%d decoded as %v", c, width, x, y, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeYUYVOddWidth(t *testing.T) {
+
+	src := flatImage(3, 2, color.RGBA{0x80, 0x40, 0xc0, 0xff})
+	src.SetRGBA(2, 0, color.RGBA{0xff, 0xff, 0xff, 0xff})
+
+	frame := EncodeRGBAToYUYV(src)
+	if len(frame) != 4*2*2 {
+		t.Fatalf("frame of %d bytes, want %d", len(frame), 4*2*2)
+	}
+
+	// The last pixel of each line is repeated to complete its pair.
+	if frame[4] != frame[6] || frame[4] != 0xff {
+		t.Errorf("padding pixel luma %d, want the last pixel's %d", frame[6], frame[4])
+	}
+}
+
+func TestEncodeRGBRoundTrip(t *testing.T) {
+
+	const width, height = 5, 3
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 37)
+		if i%4 == 3 {
+			src.Pix[i] = 0xff
+		}
+	}
+
+	for _, format := range []Format{V4L2_PIX_FMT_RGB24, V4L2_PIX_FMT_BGR24, V4L2_PIX_FMT_RGB32, V4L2_PIX_FMT_RGBA32} {
+
+		frame, err := EncodeFrame(src, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		im := image.NewRGBA(src.Rect)
+		lookupConverter(format)(frame, width, height, encodedLine(format, width), 1, im)
+
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if got, want := im.RGBAAt(x, y), src.RGBAAt(x, y); got != want {
+					t.Fatalf("%v pixel %d,%d: got %v, want %v", format, x, y, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	V4L2_EVENT_SOURCE_CHANGE uint32 = 5
+
+	V4L2_EVENT_SRC_CH_RESOLUTION uint32 = 0x0001
+
+	VIDIOC_SUBSCRIBE_EVENT uintptr = 0x4020565A
+	VIDIOC_DQEVENT                 = 0x80885659
+	VIDIOC_STREAMOFF               = 0x40045613
+)
+
+type v4l2_event_subscription struct {
+	Type, Id, Flags uint32
+	Reserved        [5]uint32
+}
+
+// v4l2_event with the payload union kept as raw bytes. The union is 8 byte
+// aligned, hence the padding after Type.
+type v4l2_event struct {
+	Type     uint32
+	_        uint32
+	U        [64]uint8
+	Pending  uint32
+	Sequence uint32
+	TvSec    int64
+	TvNsec   int64
+	Id       uint32
+	Reserved [8]uint32
+	_        uint32
+}
+
+// errSourceChanged tells readFrame that the capture format changed under
+// the buffer it was given and the frame has to be captured again.
+var errSourceChanged = errors.New("Source changed")
+
+// subscribeSourceChange asks the driver to signal input resolution changes.
+// Most webcams have no such event; only capture devices with a switchable
+// source, like HDMI receivers, report it.
+func (dev *handle) subscribeSourceChange() bool {
+
+	s := v4l2_event_subscription{Type: V4L2_EVENT_SOURCE_CHANGE}
+
+	return dev.ioctl(VIDIOC_SUBSCRIBE_EVENT, toUintptr(toBytes(s))) == nil
+}
+
+// sourceChanged drains pending events without blocking and reports whether
+// one of them was a resolution change.
+func (dev *Device) sourceChanged() bool {
+
+	changed := false
+
+	for dev.eventPending() {
+
+		b := toBytes(v4l2_event{})
+
+		if err := dev.ioctl(VIDIOC_DQEVENT, toUintptr(b)); err != nil {
+			break
+		}
+
+		var e v4l2_event
+		if err := fromBytes(b, &e); err != nil {
+			break
+		}
+
+		if e.Type == V4L2_EVENT_SOURCE_CHANGE && e.U[0]&uint8(V4L2_EVENT_SRC_CH_RESOLUTION) != 0 {
+			changed = true
+		}
+
+		if e.Pending == 0 {
+			break
+		}
+	}
+
+	return changed
+}
+
+// sizeChanged reports a dequeued frame whose size does not match the
+// negotiated format. Compressed frames vary in size and never count, nor
+// do frames of a size the format was already found unchanged for.
+func (dev *Device) sizeChanged(info FrameInfo) bool {
+
+	if dev.format.Compressed() {
+		return false
+	}
+
+	if info.Bytesused == dev.shortSize && dev.pix == dev.shortPix {
+		return false
+	}
+
+	return info.Bytesused != 0 && dev.pix.Sizeimage != 0 && info.Bytesused != dev.pix.Sizeimage
+}
+
+// renegotiate reads back the format the driver now delivers and, if it
+// differs from the one negotiated, restarts streaming with buffers sized
+// for it. It reports whether the format changed.
+func (dev *Device) renegotiate() (bool, error) {
+
+	pix, err := dev.getFormat()
+	if err != nil {
+		return false, fmt.Errorf("Failed to get format: %v", err.Error())
+	}
+
+	if pix == dev.pix {
+		return false, nil
+	}
+
+	if err := dev.restartStream(); err != nil {
+		return false, err
+	}
+
+	dev.pix = pix
+	dev.width = int(pix.Width)
+	dev.height = int(pix.Height)
+
+	if dev.scale > dev.width || dev.scale > dev.height {
+		dev.scale = 1
+	}
+
+	return true, nil
+}
+
+// restartStream stops streaming, which takes back every queued buffer,
+// releases the buffers and starts streaming again.
+func (dev *Device) restartStream() error {
+
+	if err := dev.stopStream(); err != nil {
+		return err
+	}
+
+	if err := dev.setUserptr(dev.bufType, dev.config); err != nil {
+		return fmt.Errorf("Failed to restart streaming: %v", err.Error())
+	}
+
+	dev.prepareSpare()
+
+	return nil
+}
+
+// Flush drops frames the driver and camera are still holding from before
+// a pause or a control or format change, so that the next capture shows
+// the current settings. It issues STREAMOFF, which discards everything
+// queued or in flight, and STREAMON again, keeping the buffers allocated.
+// The cost is the restart: many cameras take one or more frame intervals,
+// and some UVC cameras several hundred milliseconds, to deliver the first
+// frame after STREAMON. Devices opened WithoutStreamOn have their buffers
+// released and requested again instead. Devices capturing with read() have
+// no queue to flush and are left alone. Flush cannot be used during
+// background capture or while a stream owns the queue.
+func (dev *Device) Flush() error {
+
+	if err := dev.queueBusy("flush"); err != nil {
+		return err
+	}
+
+	if dev.readIO || !dev.streaming {
+		return nil
+	}
+
+	// Drivers opened WithoutStreamOn reject STREAMON, so their queue is
+	// dropped by releasing the buffers and requesting them again.
+	if dev.config.noStreamOn {
+		return dev.restartStream()
+	}
+
+	b := toBytes(dev.bufType)
+
+	if err := dev.ioctl(VIDIOC_STREAMOFF, toUintptr(b)); err != nil {
+		return fmt.Errorf("Failed to stop streaming: %v", err.Error())
+	}
+
+	dev.streaming = false
+
+	// STREAMOFF returns buffers to the unprepared state.
+	dev.prepared = 0
+
+	if err := dev.ioctl(VIDIOC_STREAMON, toUintptr(b)); err != nil {
+		return fmt.Errorf("Failed to restart streaming: %v", err.Error())
+	}
+
+	dev.streaming = true
+	dev.prepareSpare()
+
+	return nil
+}
+
+// stopStream stops streaming and releases the buffers, after which the
+// format can be changed.
+func (dev *Device) stopStream() error {
+
+	if dev.readIO {
+		return nil
+	}
+
+	// Drivers opened WithoutStreamOn stream implicitly and are stopped by
+	// releasing their buffers.
+	if !dev.config.noStreamOn {
+		if err := dev.ioctl(VIDIOC_STREAMOFF, toUintptr(toBytes(dev.bufType))); err != nil {
+			return fmt.Errorf("Failed to stop streaming: %v", err.Error())
+		}
+	}
+
+	dev.streaming = false
+	dev.prepared = 0
+
+	r := v4l2_requestbuffers{
+		Count:  0,
+		Type:   dev.bufType,
+		Memory: V4L2_MEMORY_USERPTR,
+	}
+
+	if err := dev.ioctl(VIDIOC_REQBUFS, toUintptr(toBytes(r))); err != nil {
+		return fmt.Errorf("Failed to release buffers: %v", err.Error())
+	}
+
+	dev.buffers = 0
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package v4l
+
+import "testing"
+
+func TestSizeChangedRemembersShortFrames(t *testing.T) {
+
+	dev := &Device{format: V4L2_PIX_FMT_YUYV}
+	dev.pix.Sizeimage = 640 * 480 * 2
+
+	short := FrameInfo{Bytesused: 640 * 479 * 2}
+	if !dev.sizeChanged(short) {
+		t.Fatal("short frame not reported")
+	}
+
+	dev.shortSize, dev.shortPix = short.Bytesused, dev.pix
+	if dev.sizeChanged(short) {
+		t.Error("short frame reported after the format was found unchanged")
+	}
+
+	dev.pix.Sizeimage = 320 * 240 * 2
+	if !dev.sizeChanged(short) {
+		t.Error("short frame not reported for a new format")
+	}
+}
@@ -0,0 +1,254 @@
+package v4l
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const (
+	V4L2_CTRL_CLASS_USER   uint32 = 0x00980000
+	V4L2_CTRL_CLASS_CAMERA        = 0x009a0000
+
+	VIDIOC_G_EXT_CTRLS uintptr = 0xC0205647
+	VIDIOC_S_EXT_CTRLS         = 0xC0205648
+)
+
+// v4l2_ext_control is packed; its value union is read as a 64-bit integer.
+type v4l2_ext_control struct {
+	Id, Size, Reserved2 uint32
+	Value               int64
+}
+
+type v4l2_ext_controls struct {
+	CtrlClass, Count, ErrorIdx uint32
+	RequestFd                  int32
+	Reserved                   uint32
+	_                          uint32
+	Controls                   uint64
+}
+
+// controlClass is V4L2_CTRL_ID2CLASS.
+func controlClass(id uint32) uint32 {
+	return id & 0x0fff0000
+}
+
+// CameraControls enumerates the controls of the camera class, such as
+// exposure, focus, pan, tilt and zoom.
+func (dev *handle) CameraControls() ([]ControlInfo, error) {
+	return dev.classControls(V4L2_CTRL_CLASS_CAMERA)
+}
+
+func (dev *handle) classControls(class uint32) ([]ControlInfo, error) {
+
+	var controls []ControlInfo
+
+	id := class | V4L2_CTRL_FLAG_NEXT_CTRL
+	for {
+
+		q, err := dev.queryControl(id)
+		if err == ErrUnsupportedControl {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if controlClass(q.Id) != class {
+			break
+		}
+
+		// The class itself is reported as a control of its own.
+		if q.Flags&V4L2_CTRL_FLAG_DISABLED == 0 && q.Type != V4L2_CTRL_TYPE_CTRL_CLASS {
+			controls = append(controls, q.info())
+		}
+
+		id = q.Id | V4L2_CTRL_FLAG_NEXT_CTRL
+	}
+
+	return controls, nil
+}
+
+// GetCameraControl reads a camera class control through the extended
+// control API.
+func (dev *handle) GetCameraControl(id uint32) (int64, error) {
+
+	if controlClass(id) != V4L2_CTRL_CLASS_CAMERA {
+		return 0, fmt.Errorf("Not a camera control: %x", id)
+	}
+
+	return dev.GetExtControl(id)
+}
+
+// GetExtControl reads a control of any class through the extended control
+// API, which also reaches the 64-bit controls G_CTRL cannot, such as the
+// sensor temperature some industrial cameras report. Read-only controls
+// read like any other. Ids outside the standard ones are specific to the
+// device and driver; Controls lists the ones a device has.
+func (dev *handle) GetExtControl(id uint32) (int64, error) {
+
+	q, err := dev.queryControl(id)
+	if err != nil {
+		return 0, err
+	}
+
+	switch q.Type {
+	case V4L2_CTRL_TYPE_INTEGER, V4L2_CTRL_TYPE_BOOLEAN, V4L2_CTRL_TYPE_MENU,
+		V4L2_CTRL_TYPE_INTEGER64, V4L2_CTRL_TYPE_BITMASK, V4L2_CTRL_TYPE_INTEGER_MENU:
+	default:
+		return 0, fmt.Errorf("Unsupported control type: %d", q.Type)
+	}
+
+	if q.Flags&V4L2_CTRL_FLAG_WRITE_ONLY != 0 {
+		return 0, fmt.Errorf("Control is write-only: %x", id)
+	}
+
+	v, err := dev.extControl(VIDIOC_G_EXT_CTRLS, controlClass(id), id, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	// Only 64-bit controls fill the whole union.
+	if q.Type != V4L2_CTRL_TYPE_INTEGER64 {
+		v = int64(int32(v))
+	}
+
+	return v, nil
+}
+
+// SetCameraControl writes a camera class control through the extended
+// control API, which also reaches the 64-bit controls S_CTRL cannot.
+func (dev *Device) SetCameraControl(id uint32, value int64) error {
+
+	if controlClass(id) != V4L2_CTRL_CLASS_CAMERA {
+		return fmt.Errorf("Not a camera control: %x", id)
+	}
+
+	_, err := dev.extControl(VIDIOC_S_EXT_CTRLS, V4L2_CTRL_CLASS_CAMERA, id, value)
+	return err
+}
+
+// extControl issues a G_EXT_CTRLS or S_EXT_CTRLS request for a single
+// control and returns the value the driver left in it.
+func (dev *handle) extControl(req uintptr, class, id uint32, value int64) (int64, error) {
+
+	cb := toBytes(v4l2_ext_control{Id: id, Value: value})
+
+	c := v4l2_ext_controls{
+		CtrlClass: class,
+		Count:     1,
+		Controls:  uint64(toUintptr(cb)),
+	}
+
+	b := toBytes(c)
+
+	err := dev.ioctl(req, toUintptr(b))
+	runtime.KeepAlive(cb)
+
+	if err != nil {
+		return 0, controlError(err)
+	}
+
+	var ctrl v4l2_ext_control
+	if err := fromBytes(cb, &ctrl); err != nil {
+		return 0, err
+	}
+
+	return ctrl.Value, nil
+}
+
+// Control is a control id and the value to set it to.
+type Control struct {
+	ID    uint32
+	Value int64
+}
+
+// ControlResult is the outcome of setting one control in ApplyControls.
+type ControlResult struct {
+	Control
+	Err error
+}
+
+// ApplyControls sets several controls in the order their clusters need:
+// auto controls first, so that turning auto exposure off makes the
+// exposure it gates writable, then the rest. Each group is written with a
+// single S_EXT_CTRLS; when the driver rejects it the controls are written
+// one at a time, so that every control gets its own result. The results
+// are in the order of controls, and the error, if any, counts the
+// failures.
+func (dev *Device) ApplyControls(controls []Control) ([]ControlResult, error) {
+
+	results := make([]ControlResult, len(controls))
+
+	var autos, rest []int
+	for i, c := range controls {
+		results[i].Control = c
+		if isAutoControl(c.ID) {
+			autos = append(autos, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+
+	for _, group := range [][]int{autos, rest} {
+
+		if len(group) == 0 || dev.setExtControls(controls, group) == nil {
+			continue
+		}
+
+		for _, i := range group {
+			results[i].Err = dev.setOneControl(controls[i])
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("Failed to set %d of %d controls", failed, len(controls))
+	}
+
+	return results, nil
+}
+
+// setExtControls writes the controls at indices in one S_EXT_CTRLS. A
+// zero class lets the request mix controls of different classes.
+func (dev *Device) setExtControls(controls []Control, indices []int) error {
+
+	ctrls := make([]v4l2_ext_control, len(indices))
+	for j, i := range indices {
+		ctrls[j] = v4l2_ext_control{Id: controls[i].ID, Value: controls[i].Value}
+	}
+
+	cb := toBytes(ctrls)
+
+	c := v4l2_ext_controls{
+		Count:    uint32(len(ctrls)),
+		Controls: uint64(toUintptr(cb)),
+	}
+
+	err := dev.ioctl(VIDIOC_S_EXT_CTRLS, toUintptr(toBytes(c)))
+	runtime.KeepAlive(cb)
+
+	return err
+}
+
+// setOneControl writes c with S_CTRL, or with S_EXT_CTRLS when it holds
+// more than S_CTRL can carry.
+func (dev *Device) setOneControl(c Control) error {
+
+	q, err := dev.queryControl(c.ID)
+	if err != nil {
+		return err
+	}
+
+	if q.Type == V4L2_CTRL_TYPE_INTEGER64 {
+		_, err := dev.extControl(VIDIOC_S_EXT_CTRLS, controlClass(c.ID), c.ID, c.Value)
+		return err
+	}
+
+	return dev.SetControl(c.ID, int32(c.Value))
+}
@@ -0,0 +1,89 @@
+package v4l
+
+const (
+	V4L2_FIELD_ANY        uint32 = 0
+	V4L2_FIELD_NONE              = 1
+	V4L2_FIELD_TOP               = 2
+	V4L2_FIELD_BOTTOM            = 3
+	V4L2_FIELD_INTERLACED        = 4
+	V4L2_FIELD_SEQ_TB            = 5
+	V4L2_FIELD_SEQ_BT            = 6
+	V4L2_FIELD_ALTERNATE         = 7
+)
+
+// Field is the field order negotiated with the driver, one of the
+// V4L2_FIELD constants.
+func (dev *Device) Field() uint32 {
+	return dev.pix.Field
+}
+
+// Weaving reports whether the driver delivers the fields of interlaced
+// frames in separate buffers, V4L2_FIELD_ALTERNATE, which every capture
+// then weaves back into full frames. Each frame takes two buffers, so
+// frames arrive at half the rate the driver delivers buffers at. The
+// chroma lines of NV12 and NV21 fields are woven like their luma lines,
+// each field's chroma standing for the frame lines of its own parity.
+func (dev *Device) Weaving() bool {
+	return dev.pix.Field == V4L2_FIELD_ALTERNATE && !dev.format.Compressed()
+}
+
+// weaveInto reads fields with read until a top and a bottom field have
+// arrived one after the other, in either order, and weaves their lines
+// into frame. The info returned is that of the second field. A buffer
+// that turns out not to hold a field is passed on as the frame.
+func (dev *Device) weaveInto(frame []byte, read func([]byte) (FrameInfo, error)) (FrameInfo, error) {
+
+	if len(dev.field) != dev.frameSize() {
+		dev.field = frameBuffer(dev.frameSize())
+	}
+
+	field := dev.field
+	prev := V4L2_FIELD_ANY
+
+	for {
+
+		info, err := read(field)
+		if err != nil {
+			return info, err
+		}
+
+		var first int
+		switch info.Field {
+		case V4L2_FIELD_TOP:
+			first = 0
+		case V4L2_FIELD_BOTTOM:
+			first = 1
+		default:
+			copy(frame, field)
+			return info, nil
+		}
+
+		stride := dev.stride()
+		lines := (dev.height - first + 1) / 2
+		weaveLines(frame, field, stride, first, dev.height)
+
+		if semiPlanar(dev.format) {
+			weaveLines(frame[stride*dev.height:], field[stride*lines:], stride, first, (dev.height+1)/2)
+		}
+
+		if prev != V4L2_FIELD_ANY && prev != info.Field {
+			info.Bytesused = uint32(stride * dev.height)
+			if n := dev.layoutSize(); n != 0 {
+				info.Bytesused = uint32(n)
+			}
+			return info, nil
+		}
+
+		prev = info.Field
+	}
+}
+
+// weaveLines copies the lines of field, stride bytes apart, into every
+// other line of a plane of height lines in frame, starting with line
+// first.
+func weaveLines(frame, field []byte, stride, first, height int) {
+
+	for y, i := first, 0; y < height && (i+1)*stride <= len(field) && (y+1)*stride <= len(frame); y, i = y+2, i+1 {
+		copy(frame[y*stride:(y+1)*stride], field[i*stride:])
+	}
+}
@@ -0,0 +1,73 @@
+package v4l
+
+import (
+	"testing"
+)
+
+func TestWeaveInto(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		format Format
+		line   int
+		planes []int // lines of each plane of a frame
+	}{
+		{"YUYV", V4L2_PIX_FMT_YUYV, 8, []int{4}},
+		{"NV12", V4L2_PIX_FMT_NV12, 4, []int{4, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dev := &Device{format: tt.format, width: 4, height: 4, bufType: V4L2_BUF_TYPE_VIDEO_CAPTURE}
+			dev.pix.Field = V4L2_FIELD_ALTERNATE
+
+			if !dev.Weaving() {
+				t.Fatal("Weaving = false for an alternating stream")
+			}
+
+			// Each line of a field is filled with its frame line's number,
+			// offset by 100 for every plane after the first.
+			fields := []uint32{V4L2_FIELD_BOTTOM, V4L2_FIELD_TOP}
+			read := func(field []byte) (FrameInfo, error) {
+
+				f := fields[0]
+				fields = fields[1:]
+				first := int(f - V4L2_FIELD_TOP)
+
+				off := 0
+				for p, lines := range tt.planes {
+					for y := first; y < lines; y += 2 {
+						for x := 0; x < tt.line; x++ {
+							field[off+x] = uint8(p*100 + y)
+						}
+						off += tt.line
+					}
+				}
+
+				return FrameInfo{Field: f}, nil
+			}
+
+			frame := make([]byte, dev.frameSize())
+			info, err := dev.weaveInto(frame, read)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Field != V4L2_FIELD_TOP {
+				t.Errorf("Field = %d, want the second field's", info.Field)
+			}
+
+			off := 0
+			for p, lines := range tt.planes {
+				for y := 0; y < lines; y++ {
+					for x := 0; x < tt.line; x++ {
+						if got, want := frame[off+x], uint8(p*100+y); got != want {
+							t.Fatalf("plane %d line %d byte %d = %d, want %d", p, y, x, got, want)
+						}
+					}
+					off += tt.line
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package v4l
+
+import "fmt"
+
+// CaptureFormat is the format negotiated with the driver.
+type CaptureFormat struct {
+	PixelFormat   Format
+	Width, Height int
+	Field         uint32
+	BytesPerLine  int
+	SizeImage     int
+}
+
+// CurrentFormat returns the format the driver last reported, without
+// asking it again.
+func (dev *Device) CurrentFormat() CaptureFormat {
+	return CaptureFormat{
+		PixelFormat:  Format(dev.pix.Pixelformat),
+		Width:        dev.width,
+		Height:       dev.height,
+		Field:        dev.pix.Field,
+		BytesPerLine: int(dev.pix.Bytesperline),
+		SizeImage:    int(dev.pix.Sizeimage),
+	}
+}
+
+// RefreshFormat queries the driver for its current format, restarting the
+// stream with new buffers if the source has changed it, and returns it.
+func (dev *Device) RefreshFormat() (CaptureFormat, error) {
+
+	if _, err := dev.renegotiate(); err != nil {
+		return CaptureFormat{}, err
+	}
+
+	return dev.CurrentFormat(), nil
+}
+
+// FormatAdjustment compares the capture format requested with the one the
+// driver settled on, as S_FMT changes a request to the nearest format it
+// supports rather than failing.
+type FormatAdjustment struct {
+	Requested CaptureFormat
+	Actual    CaptureFormat
+}
+
+func (a FormatAdjustment) PixelFormatChanged() bool {
+	return a.Requested.PixelFormat != a.Actual.PixelFormat
+}
+
+func (a FormatAdjustment) SizeChanged() bool {
+	return a.Requested.Width != a.Actual.Width || a.Requested.Height != a.Actual.Height
+}
+
+// FieldChanged reports a field order other than the one requested. A
+// request for V4L2_FIELD_ANY accepts any.
+func (a FormatAdjustment) FieldChanged() bool {
+	return a.Requested.Field != V4L2_FIELD_ANY && a.Requested.Field != a.Actual.Field
+}
+
+// Adjusted reports whether the driver changed the request at all, apart
+// from the line and image sizes it always fills in.
+func (a FormatAdjustment) Adjusted() bool {
+	return a.PixelFormatChanged() || a.SizeChanged() || a.FieldChanged()
+}
+
+func formatAdjustment(format Format, width, height int, config config, pix v4l2_pix_format) FormatAdjustment {
+	return FormatAdjustment{
+		Requested: CaptureFormat{
+			PixelFormat:  format,
+			Width:        width,
+			Height:       height,
+			Field:        config.field,
+			BytesPerLine: config.bytesPerLine,
+			SizeImage:    config.sizeImage,
+		},
+		Actual: CaptureFormat{
+			PixelFormat:  Format(pix.Pixelformat),
+			Width:        int(pix.Width),
+			Height:       int(pix.Height),
+			Field:        pix.Field,
+			BytesPerLine: int(pix.Bytesperline),
+			SizeImage:    int(pix.Sizeimage),
+		},
+	}
+}
+
+// FormatAdjustment reports how the driver adjusted the format last
+// requested, by Open or SetFormat, such as a 1920x1080 request that came
+// out as 1280x720.
+func (dev *Device) FormatAdjustment() FormatAdjustment {
+	return dev.adjustment
+}
+
+// SetFormat stops the stream, sets a new capture format and restarts
+// streaming in it. The driver may substitute the nearest format it
+// supports, which the device then captures in, as long as it is one with a
+// converter; the adjustment returned says what changed. Buffers from
+// earlier captures are sized for the old format.
+func (dev *Device) SetFormat(format Format, width, height int) (FormatAdjustment, error) {
+
+	if lookupConverter(format) == nil && !format.Compressed() {
+		return FormatAdjustment{}, fmt.Errorf("Unsupported format: %v", format)
+	}
+
+	if err := dev.queueBusy("change format"); err != nil {
+		return FormatAdjustment{}, err
+	}
+
+	if err := dev.switchFormat(format, width, height, dev.config); err != nil {
+		return FormatAdjustment{}, err
+	}
+
+	if dev.scale > dev.width || dev.scale > dev.height {
+		dev.scale = 1
+	}
+
+	return dev.adjustment, nil
+}
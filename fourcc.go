@@ -0,0 +1,61 @@
+package v4l
+
+import (
+	"strings"
+)
+
+// Format is a V4L2 pixel format, a four character code packed first
+// character in the low byte.
+type Format uint32
+
+// FourCC packs a four character code such as "YUYV" into a Format. Shorter
+// codes are padded with spaces, like "Y16 ".
+func FourCC(s string) Format {
+
+	b := []byte(s + "    ")[:4]
+
+	return Format(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24)
+}
+
+// FourCCString returns the four character code of a pixel format, without
+// trailing spaces.
+func FourCCString(code uint32) string {
+
+	b := []byte{byte(code), byte(code >> 8), byte(code >> 16), byte(code >> 24)}
+
+	return strings.TrimRight(string(b), " ")
+}
+
+func (f Format) String() string {
+	return FourCCString(uint32(f))
+}
+
+// Compressed reports formats whose frames vary in size. Of them only
+// V4L2_PIX_FMT_MJPEG has a converter to RGBA.
+func (f Format) Compressed() bool {
+	return f == V4L2_PIX_FMT_MJPEG || f == V4L2_PIX_FMT_H264
+}
+
+// BitsPerPixel is the average storage per pixel across all planes, or 0
+// for compressed and unknown formats.
+func (f Format) BitsPerPixel() int {
+
+	if semiPlanar(f) {
+		return 12
+	}
+
+	// Eight pixels take as many bytes as one takes bits.
+	return packedLine(f, 8)
+}
+
+// Planes is the number of planes a frame of the format is stored in. The
+// semi-planar formats count two, luma and interleaved chroma, although
+// they are captured into a single buffer.
+func (f Format) Planes() int {
+
+	if semiPlanar(f) {
+		return 2
+	}
+
+	return 1
+}
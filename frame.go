@@ -0,0 +1,212 @@
+package v4l
+
+import (
+	"image"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	V4L2_BUF_FLAG_MAPPED   uint32 = 0x00000001
+	V4L2_BUF_FLAG_QUEUED          = 0x00000002
+	V4L2_BUF_FLAG_DONE            = 0x00000004
+	V4L2_BUF_FLAG_KEYFRAME        = 0x00000008
+	V4L2_BUF_FLAG_PFRAME          = 0x00000010
+	V4L2_BUF_FLAG_BFRAME          = 0x00000020
+	V4L2_BUF_FLAG_ERROR           = 0x00000040
+	V4L2_BUF_FLAG_LAST            = 0x00100000
+
+	V4L2_BUF_FLAG_TIMESTAMP_MASK      = 0x0000e000
+	V4L2_BUF_FLAG_TIMESTAMP_UNKNOWN   = 0x00000000
+	V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC = 0x00002000
+	V4L2_BUF_FLAG_TIMESTAMP_COPY      = 0x00004000
+)
+
+// FrameInfo is what the driver reported about a dequeued frame.
+type FrameInfo struct {
+	Index     uint32
+	Sequence  uint32
+	Flags     uint32
+	Field     uint32
+	Bytesused uint32
+
+	// Timestamp is when the driver captured the frame, as an offset from
+	// the epoch of the driver's clock: CLOCK_MONOTONIC when Monotonic
+	// reports true, otherwise usually the wall clock.
+	Timestamp time.Duration
+
+	// Width and Height are the dimensions the frame was captured at, which
+	// change when a capture card's source switches resolution.
+	Width, Height int
+}
+
+func frameInfo(b v4l2_buffer) FrameInfo {
+	return FrameInfo{
+		Index:     b.Index,
+		Sequence:  b.Sequence,
+		Flags:     b.Flags,
+		Field:     b.Field,
+		Bytesused: b.Bytesused,
+		Timestamp: time.Duration(b.TvSec)*time.Second + time.Duration(b.TvUsec)*time.Microsecond,
+	}
+}
+
+// KeyFrame reports a compressed frame that decodes on its own.
+func (f FrameInfo) KeyFrame() bool {
+	return f.Flags&V4L2_BUF_FLAG_KEYFRAME != 0
+}
+
+func (f FrameInfo) PFrame() bool {
+	return f.Flags&V4L2_BUF_FLAG_PFRAME != 0
+}
+
+func (f FrameInfo) BFrame() bool {
+	return f.Flags&V4L2_BUF_FLAG_BFRAME != 0
+}
+
+// Error reports that the driver flagged the frame data as unreliable.
+func (f FrameInfo) Error() bool {
+	return f.Flags&V4L2_BUF_FLAG_ERROR != 0
+}
+
+// Monotonic reports whether Timestamp was taken from CLOCK_MONOTONIC, which
+// unlike the wall clock is not moved by clock adjustments.
+func (f FrameInfo) Monotonic() bool {
+	return f.Flags&V4L2_BUF_FLAG_TIMESTAMP_MASK == V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC
+}
+
+// Time returns the capture time as a wall clock time.Time. A monotonic
+// timestamp is placed relative to time.Now, and the result carries Go's
+// monotonic clock reading, so durations between frames measured with Sub
+// stay correct across clock adjustments. Other timestamps are taken as
+// wall clock time as is.
+func (f FrameInfo) Time() time.Time {
+
+	if !f.Monotonic() {
+		return time.Unix(0, int64(f.Timestamp))
+	}
+
+	now := time.Now()
+
+	return now.Add(f.Timestamp - monotonicNow())
+}
+
+// CLOCK_MONOTONIC, the clock id V4L2 monotonic timestamps are taken on.
+const clockMonotonic = 1
+
+func monotonicNow() time.Duration {
+
+	var ts syscall.Timespec
+
+	syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0)
+
+	return time.Duration(ts.Nano())
+}
+
+// Frame is an image captured by CaptureFrame together with the capture
+// buffer it was read from. The buffer belongs to the Frame until Release
+// hands it back for the next capture; frames that are never released leave
+// every later capture to allocate a buffer of its own. Image may share the
+// buffer's memory, so it must not be used after Release.
+type Frame struct {
+	Image *image.RGBA
+	Info  FrameInfo
+
+	// Index is the index of the buffer the driver filled.
+	Index uint32
+
+	dev *Device
+	buf []byte
+}
+
+// CaptureFrame captures a frame like GetFrame but keeps the capture buffer
+// with the returned Frame rather than discarding it, so that repeated
+// captures reuse released buffers instead of allocating. Frames in the
+// device's own RGBA layout are not copied at all.
+func (dev *Device) CaptureFrame() (*Frame, error) {
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, err
+	}
+
+	for {
+
+		buf := dev.spareBuffer()
+
+		info, err := dev.readFrameInto(buf)
+		if err == errSourceChanged {
+			continue
+		}
+		if err != nil {
+			dev.putBuffer(buf)
+			return nil, err
+		}
+
+		return dev.newFrame(buf, info), nil
+	}
+}
+
+// newFrame converts the captured buf into a Frame that owns it.
+func (dev *Device) newFrame(buf []byte, info FrameInfo) *Frame {
+
+	var im *image.RGBA
+	if dev.zeroCopy() {
+		im = &image.RGBA{Pix: buf, Stride: dev.stride(), Rect: dev.bounds()}
+		setOpaque(im)
+	} else {
+		im = image.NewRGBA(dev.bounds())
+		dev.convert(buf, im)
+	}
+
+	return &Frame{
+		Image: dev.transformed(im, info),
+		Info:  info,
+		Index: info.Index,
+		dev:   dev,
+		buf:   buf,
+	}
+}
+
+// Release hands the frame's buffer back to the device for another capture.
+// Releasing a frame twice has no effect.
+func (f *Frame) Release() {
+
+	if f.buf == nil {
+		return
+	}
+
+	f.dev.putBuffer(f.buf)
+	f.buf = nil
+}
+
+// spareBuffer returns a released buffer of the current frame size, or a
+// new one when none is left. Buffers sized for a format the source has
+// since switched away from are dropped.
+func (dev *Device) spareBuffer() []byte {
+
+	dev.spareMu.Lock()
+	defer dev.spareMu.Unlock()
+
+	size := dev.frameSize()
+
+	for len(dev.spare) > 0 {
+
+		buf := dev.spare[len(dev.spare)-1]
+		dev.spare = dev.spare[:len(dev.spare)-1]
+
+		if len(buf) == size {
+			return buf
+		}
+	}
+
+	return frameBuffer(size)
+}
+
+func (dev *Device) putBuffer(buf []byte) {
+
+	dev.spareMu.Lock()
+	defer dev.spareMu.Unlock()
+
+	dev.spare = append(dev.spare, buf)
+}
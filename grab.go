@@ -0,0 +1,164 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+)
+
+const VIDIOC_TRY_FMT uintptr = 0xC0D05640
+
+// GrabAs captures a single frame in another format and size, such as a
+// full resolution still from a low resolution preview, and then returns
+// the device to the format it was streaming in. This is heavyweight: the
+// stream is stopped and its buffers released twice, and many cameras take
+// a few frames to settle after a format change. The device's scale is not
+// applied to the grabbed frame; its transform is. The format is checked
+// with TRY_FMT first, so one the driver cannot deliver fails without
+// touching the stream.
+func (dev *Device) GrabAs(format Format, width, height int) (*image.RGBA, error) {
+
+	if lookupConverter(format) == nil {
+		return nil, fmt.Errorf("Unsupported format: %v", format)
+	}
+
+	if err := dev.queueBusy("change format"); err != nil {
+		return nil, err
+	}
+
+	if err := dev.tryFormat(format, width, height); err != nil {
+		return nil, err
+	}
+
+	format0, width0, height0, scale0 := dev.format, dev.width, dev.height, dev.scale
+
+	restore := func() error {
+
+		err := dev.switchFormat(format0, width0, height0, dev.config)
+
+		dev.scale = scale0
+		if dev.scale > dev.width || dev.scale > dev.height {
+			dev.scale = 1
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to restore format: %v", err.Error())
+		}
+
+		return nil
+	}
+
+	// Alignment options are meant for the regular stream's format.
+	grab := dev.config
+	grab.bytesPerLine, grab.sizeImage = 0, 0
+
+	// A switch that fails part way may have stopped the stream or set the
+	// new format already, so the old one is restored all the same.
+	if err := dev.switchFormat(format, width, height, grab); err != nil {
+		if rerr := restore(); rerr != nil {
+			return nil, fmt.Errorf("%v; %v", err.Error(), rerr.Error())
+		}
+		return nil, err
+	}
+
+	dev.scale = 1
+	im, err := dev.GetFrame()
+
+	if rerr := restore(); rerr != nil {
+		return nil, rerr
+	}
+
+	return im, err
+}
+
+// queueBusy fails when background capture or a stream owns the device's
+// queue, which the action, such as a format change, would pull from under
+// it.
+func (dev *Device) queueBusy(action string) error {
+
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+
+	if dev.background != nil {
+		return fmt.Errorf("Cannot %s during background capture", action)
+	}
+
+	if dev.streams > 0 {
+		return fmt.Errorf("Cannot %s while streaming", action)
+	}
+
+	return nil
+}
+
+// beginStream and endStream bracket a goroutine that owns the queue.
+func (dev *Device) beginStream() {
+
+	dev.mu.Lock()
+	dev.streams++
+	dev.mu.Unlock()
+}
+
+func (dev *Device) endStream() {
+
+	dev.mu.Lock()
+	dev.streams--
+	dev.mu.Unlock()
+}
+
+// tryFormat asks the driver whether it can deliver format at exactly the
+// given size, without changing anything.
+func (dev *Device) tryFormat(format Format, width, height int) error {
+
+	f := v4l2_format{
+		Type: V4L2_BUF_TYPE_VIDEO_CAPTURE,
+		Pix: v4l2_pix_format{
+			Width:       uint32(width),
+			Height:      uint32(height),
+			Pixelformat: uint32(format),
+		},
+	}
+
+	b := toBytes(f)
+
+	if err := dev.ioctl(VIDIOC_TRY_FMT, toUintptr(b)); err != nil {
+		return fmt.Errorf("Failed to try format: %v", err.Error())
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return err
+	}
+
+	if Format(f.Pix.Pixelformat) != format || int(f.Pix.Width) != width || int(f.Pix.Height) != height {
+		return fmt.Errorf("Driver offers %v %dx%d instead of %v %dx%d",
+			Format(f.Pix.Pixelformat), f.Pix.Width, f.Pix.Height, format, width, height)
+	}
+
+	return nil
+}
+
+// switchFormat stops the stream, sets a new format and restarts streaming
+// with buffers for it.
+func (dev *Device) switchFormat(format Format, width, height int, config config) error {
+
+	if err := dev.stopStream(); err != nil {
+		return err
+	}
+
+	pix, err := dev.setFormat(format, width, height, config)
+	if err != nil {
+		return fmt.Errorf("Failed to set format: %v", err.Error())
+	}
+
+	dev.adjustment = formatAdjustment(format, width, height, config, pix)
+	dev.format = Format(pix.Pixelformat)
+	dev.pix = pix
+	dev.width = int(pix.Width)
+	dev.height = int(pix.Height)
+
+	if err := dev.setUserptr(dev.bufType, dev.config); err != nil {
+		return fmt.Errorf("Failed to restart streaming: %v", err.Error())
+	}
+
+	dev.prepareSpare()
+
+	return nil
+}
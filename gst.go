@@ -0,0 +1,55 @@
+package v4l
+
+import (
+	"fmt"
+)
+
+// gstFormats names the GStreamer video/x-raw format of each pixel format
+// whose memory layout GStreamer shares.
+var gstFormats = map[Format]string{
+	V4L2_PIX_FMT_YUYV:   "YUY2",
+	V4L2_PIX_FMT_UYVY:   "UYVY",
+	V4L2_PIX_FMT_YVYU:   "YVYU",
+	V4L2_PIX_FMT_VYUY:   "VYUY",
+	V4L2_PIX_FMT_NV12:   "NV12",
+	V4L2_PIX_FMT_NV21:   "NV21",
+	V4L2_PIX_FMT_RGBA32: "RGBA",
+	V4L2_PIX_FMT_RGB24:  "RGB",
+	V4L2_PIX_FMT_BGR24:  "BGR",
+	V4L2_PIX_FMT_RGB32:  "xRGB",
+	V4L2_PIX_FMT_Y210:   "Y210",
+	V4L2_PIX_FMT_Y16:    "GRAY16_LE",
+}
+
+// GstCaps describes the device's negotiated format as GStreamer caps, for
+// configuring an appsrc fed the device's raw frames. The caps hold no
+// stride, so raw video frames must have their lines back to back, as
+// RecordRaw writes them; frames GetRawFrame returns keep the padding a
+// driver adds after each line, which BytesPerLine beyond the packed line
+// size reveals, and need repacking then. The framerate is left out when
+// the driver does not report one.
+func (dev *Device) GstCaps() (string, error) {
+
+	var caps string
+
+	switch dev.format {
+	case V4L2_PIX_FMT_MJPEG:
+		caps = "image/jpeg"
+	case V4L2_PIX_FMT_H264:
+		caps = "video/x-h264,stream-format=byte-stream,alignment=au"
+	default:
+		name, ok := gstFormats[dev.format]
+		if !ok {
+			return "", fmt.Errorf("No GStreamer format for %v", dev.format)
+		}
+		caps = "video/x-raw,format=" + name
+	}
+
+	caps += fmt.Sprintf(",width=%d,height=%d", dev.width, dev.height)
+
+	if f, err := dev.timePerFrame(); err == nil {
+		caps += fmt.Sprintf(",framerate=%d/%d", f.Denominator, f.Numerator)
+	}
+
+	return caps, nil
+}
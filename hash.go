@@ -0,0 +1,57 @@
+package v4l
+
+import (
+	"hash/fnv"
+	"image"
+)
+
+// FrameHash returns a 64-bit FNV-1a hash of the pixels inside im's bounds.
+// Identical frames hash the same, so a recorder can skip a frame whose hash
+// matches the previous one. Sensor noise makes live frames of a static scene
+// differ slightly; FrameDifference tolerates that.
+func FrameHash(im *image.RGBA) uint64 {
+
+	h := fnv.New64a()
+
+	b := im.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := im.PixOffset(b.Min.X, y)
+		h.Write(im.Pix[i : i+b.Dx()*4])
+	}
+
+	return h.Sum64()
+}
+
+// FrameDifference returns the mean absolute difference of the color
+// channels of a and b, from 0 for identical frames to 255. Frames of
+// different sizes differ by 255.
+func FrameDifference(a, b *image.RGBA) float64 {
+
+	r := a.Bounds()
+	if r.Size() != b.Bounds().Size() {
+		return 255
+	}
+	if r.Empty() {
+		return 0
+	}
+
+	var sum uint64
+
+	for y := 0; y < r.Dy(); y++ {
+
+		pa := a.Pix[a.PixOffset(r.Min.X, r.Min.Y+y):]
+		pb := b.Pix[b.PixOffset(b.Rect.Min.X, b.Rect.Min.Y+y):]
+
+		for i := 0; i < r.Dx()*4; i += 4 {
+			for c := 0; c < 3; c++ {
+				d := int(pa[i+c]) - int(pb[i+c])
+				if d < 0 {
+					d = -d
+				}
+				sum += uint64(d)
+			}
+		}
+	}
+
+	return float64(sum) / float64(r.Dx()*r.Dy()*3)
+}
@@ -0,0 +1,73 @@
+package v4l
+
+import (
+	"image"
+	"image/color"
+)
+
+// Histogram counts the pixels of im at each luma level. Luma is computed
+// with the JFIF weights the YUV conversions use, so up to rounding it
+// agrees with HistogramYUYV on the frame im was converted from.
+func Histogram(im *image.RGBA) [256]uint32 {
+
+	var h [256]uint32
+
+	b := im.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+
+		row := im.Pix[im.PixOffset(b.Min.X, y):]
+
+		for i := 0; i < b.Dx()*4; i += 4 {
+			l, _, _ := color.RGBToYCbCr(row[i+0], row[i+1], row[i+2])
+			h[l]++
+		}
+	}
+
+	return h
+}
+
+// HistogramYUYV counts the luma levels of a raw YUYV frame as returned by
+// GetRawFrame, reading the Y samples directly without converting.
+func HistogramYUYV(frame []byte) [256]uint32 {
+
+	// Without padding the frame's lines run together into one.
+	h, _ := lumaHistogram(frame, V4L2_PIX_FMT_YUYV, len(frame)/2, 1, len(frame))
+
+	return h
+}
+
+// lumaHistogram counts the luma levels of a raw frame in format, reading the
+// Y samples directly. It reports false for formats without a luma plane
+// or packed luma samples; a nil frame just checks the format.
+func lumaHistogram(frame []byte, format Format, width, height, stride int) ([256]uint32, bool) {
+
+	var h [256]uint32
+
+	if l, ok := packed422Layouts[format]; ok {
+
+		for y := 0; y < height && (y+1)*stride <= len(frame); y++ {
+
+			row := frame[y*stride:]
+
+			for i := 0; i+4 <= width*2; i += 4 {
+				h[row[i+l.y0]]++
+				h[row[i+l.y1]]++
+			}
+		}
+
+		return h, true
+	}
+
+	if semiPlanar(format) {
+
+		for y := 0; y < height && (y+1)*stride <= len(frame); y++ {
+			for _, v := range frame[y*stride : y*stride+width] {
+				h[v]++
+			}
+		}
+
+		return h, true
+	}
+
+	return h, false
+}
@@ -0,0 +1,55 @@
+package v4l
+
+import (
+	"image"
+	"testing"
+)
+
+func TestHistogramYUYV(t *testing.T) {
+
+	frame := make([]byte, 64*4*2)
+	for i := range frame {
+		frame[i] = uint8(i * 7)
+	}
+
+	var want [256]uint32
+	for i := 0; i < len(frame); i += 2 {
+		want[frame[i]]++
+	}
+
+	if got := HistogramYUYV(frame); got != want {
+		t.Error("histogram differs from the frame's Y samples")
+	}
+}
+
+func TestHistogramGradient(t *testing.T) {
+
+	// A gray gradient with each level in one column of four pixels.
+	im := image.NewRGBA(image.Rect(0, 0, 256, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 256; x++ {
+			p := im.PixOffset(x, y)
+			im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = uint8(x), uint8(x), uint8(x), 0xff
+		}
+	}
+
+	h := Histogram(im)
+
+	for level, n := range h {
+		if n != 4 {
+			t.Errorf("level %d: %d pixels, want 4", level, n)
+		}
+	}
+
+	// The same gradient as YUYV, whose luma is read as is.
+	frame := make([]byte, 256*2)
+	for x := 0; x < 256; x++ {
+		frame[x*2], frame[x*2+1] = uint8(x), 128
+	}
+
+	for level, n := range HistogramYUYV(frame) {
+		if n != 1 {
+			t.Errorf("YUYV level %d: %d pixels, want 1", level, n)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package v4l
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ToHSV converts im to hue, saturation and value, three bytes per pixel in
+// row order. Hue spans a full turn over 0-255, so red sits at both ends.
+func ToHSV(im *image.RGBA) []byte {
+
+	b := im.Bounds()
+	out := make([]byte, b.Dx()*b.Dy()*3)
+
+	p := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+
+		row := im.Pix[im.PixOffset(b.Min.X, y):]
+
+		for i := 0; i < b.Dx()*4; i += 4 {
+			out[p+0], out[p+1], out[p+2] = rgbToHSV(row[i+0], row[i+1], row[i+2])
+			p += 3
+		}
+	}
+
+	return out
+}
+
+// YUYVToHSV converts a raw YUYV frame straight to the layout ToHSV
+// returns, without building an RGBA image in between.
+func YUYVToHSV(frame []byte, width, height int) []byte {
+
+	out := make([]byte, width*height*3)
+	l := packed422Layouts[V4L2_PIX_FMT_YUYV]
+
+	for y := 0; y < height && y*width*2 < len(frame); y++ {
+
+		row := frame[y*width*2:]
+
+		for x := 0; x < width; x++ {
+
+			i := (x / 2) * 4
+			if i+4 > len(row) {
+				break
+			}
+
+			ys := row[i+l.y0]
+			if x%2 == 1 {
+				ys = row[i+l.y1]
+			}
+
+			p := (y*width + x) * 3
+			out[p+0], out[p+1], out[p+2] = rgbToHSV(color.YCbCrToRGB(ys, row[i+l.cb], row[i+l.cr]))
+		}
+	}
+
+	return out
+}
+
+func rgbToHSV(r, g, b uint8) (uint8, uint8, uint8) {
+
+	max, min := r, r
+	for _, c := range [2]uint8{g, b} {
+		if c > max {
+			max = c
+		}
+		if c < min {
+			min = c
+		}
+	}
+
+	d := int(max) - int(min)
+	if d == 0 {
+		return 0, 0, max
+	}
+
+	s := uint8(d * 255 / int(max))
+
+	// The hue is worked out in degrees, each sixth of the circle 60 wide,
+	// and brought into one turn whichever side of red it falls.
+	var h float64
+	switch max {
+	case r:
+		h = 60 * float64(int(g)-int(b)) / float64(d)
+	case g:
+		h = 120 + 60*float64(int(b)-int(r))/float64(d)
+	default:
+		h = 240 + 60*float64(int(r)-int(g))/float64(d)
+	}
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+
+	// A turn spans 256 units, so hues just short of red stay below 256.
+	return uint8(h * 256 / 360), s, max
+}
@@ -0,0 +1,23 @@
+package v4l
+
+import "testing"
+
+func TestRGBToHSVHue(t *testing.T) {
+
+	for _, c := range []struct {
+		r, g, b uint8
+		h       uint8
+	}{
+		{255, 0, 0, 0},
+		{255, 255, 0, 42},
+		{0, 255, 0, 85},
+		{0, 255, 255, 128},
+		{0, 0, 255, 170},
+		{255, 0, 255, 213},
+		{255, 0, 1, 255},
+	} {
+		if h, _, _ := rgbToHSV(c.r, c.g, c.b); h != c.h {
+			t.Errorf("hue of %d,%d,%d = %d, want %d", c.r, c.g, c.b, h, c.h)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package v4l
+
+import (
+	"image"
+	"image/color"
+	"time"
+)
+
+// I420Frame is a frame in the planar 4:2:0 layout video encoders such as
+// those behind WebRTC take, with how long it is to be shown.
+type I420Frame struct {
+	Image     *image.YCbCr
+	Timestamp time.Duration
+	Duration  time.Duration
+}
+
+// I420Source captures a device's frames as I420. The frames carry the
+// driver timestamp and the time since the previous frame, which is the
+// duration a WebRTC sample expects.
+type I420Source struct {
+	dev      *Device
+	interval time.Duration
+	last     time.Duration
+}
+
+// defaultInterval paces sources whose driver does not report a frame
+// interval.
+const defaultInterval = time.Second / 30
+
+// I420Source returns a source reading frames from dev.
+func (dev *Device) I420Source() (*I420Source, error) {
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, err
+	}
+
+	interval, err := dev.FrameInterval()
+	if err != nil {
+		interval = defaultInterval
+	}
+
+	return &I420Source{dev: dev, interval: interval}, nil
+}
+
+// Interval is the time between frames the source is paced at.
+func (s *I420Source) Interval() time.Duration {
+	return s.interval
+}
+
+// Read captures the next frame.
+func (s *I420Source) Read() (I420Frame, error) {
+
+	frame, info, err := s.dev.readFrame()
+	if err != nil {
+		return I420Frame{}, err
+	}
+
+	im := image.NewYCbCr(image.Rect(0, 0, s.dev.width, s.dev.height), image.YCbCrSubsampleRatio420)
+	toI420(frame, s.dev.format, s.dev.width, s.dev.height, s.dev.stride(), im)
+
+	d := s.interval
+	if s.last != 0 && info.Timestamp > s.last {
+		d = info.Timestamp - s.last
+	}
+	s.last = info.Timestamp
+
+	return I420Frame{Image: im, Timestamp: info.Timestamp, Duration: d}, nil
+}
+
+// Run reads a frame at every tick of the source's interval and passes it
+// to fn until stop is closed or either fails.
+func (s *I420Source) Run(stop <-chan struct{}, fn func(I420Frame) error) error {
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		f, err := s.Read()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+}
+
+// I420Layout describes the planes of a frame returned by GetI420: a full
+// resolution Y plane followed by Cb and Cr planes of half the width and
+// height, rounded up, with no padding between lines or planes. This is
+// ffmpeg's rawvideo yuv420p.
+type I420Layout struct {
+	Size             int
+	YOffset, YStride int
+	CbOffset         int
+	CrOffset         int
+	CStride          int
+}
+
+// I420Layout returns the layout of the frames GetI420 returns at the
+// device's current size.
+func (dev *Device) I420Layout() I420Layout {
+
+	cw, ch := (dev.width+1)/2, (dev.height+1)/2
+	ySize := dev.width * dev.height
+
+	return I420Layout{
+		Size:     ySize + 2*cw*ch,
+		YStride:  dev.width,
+		CbOffset: ySize,
+		CrOffset: ySize + cw*ch,
+		CStride:  cw,
+	}
+}
+
+// GetI420 captures a frame and returns it as contiguous I420 bytes, laid
+// out as I420Layout describes, for piping into an encoder such as
+// `ffmpeg -f rawvideo -pix_fmt yuv420p -s WxH -i -`.
+func (dev *Device) GetI420() ([]byte, error) {
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, err
+	}
+
+	frame, _, err := dev.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	l := dev.I420Layout()
+	buf := make([]byte, l.Size)
+
+	im := &image.YCbCr{
+		Y:              buf[:l.CbOffset],
+		Cb:             buf[l.CbOffset:l.CrOffset],
+		Cr:             buf[l.CrOffset:],
+		YStride:        l.YStride,
+		CStride:        l.CStride,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           image.Rect(0, 0, dev.width, dev.height),
+	}
+
+	toI420(frame, dev.format, dev.width, dev.height, dev.stride(), im)
+
+	return buf, nil
+}
+
+// toI420 converts a frame into im, which must be 4:2:0 and width x height.
+// YUV formats are resampled directly; anything else goes through RGBA.
+func toI420(frame []byte, format Format, width, height, stride int, im *image.YCbCr) {
+
+	if l, ok := packed422Layouts[format]; ok {
+		frame = padFrame(frame, stride*height+4)
+		packed422ToI420(frame, width, height, stride, l, im)
+		return
+	}
+
+	if semiPlanar(format) {
+		frame = padFrame(frame, stride*(height+(height+1)/2)+2)
+		for y := 0; y < height; y++ {
+			copy(im.Y[y*im.YStride:y*im.YStride+width], frame[y*stride:])
+		}
+		for y := 0; y < (height+1)/2; y++ {
+			for x := 0; x < (width+1)/2; x++ {
+				_, cb, cr := nv12Sample(frame, stride, height, x*2, y*2, format == V4L2_PIX_FMT_NV21)
+				im.Cb[y*im.CStride+x], im.Cr[y*im.CStride+x] = cb, cr
+			}
+		}
+		return
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	lookupConverter(format)(frame, width, height, stride, 1, rgba)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := rgba.PixOffset(x, y)
+			yy, cb, cr := color.RGBToYCbCr(rgba.Pix[p+0], rgba.Pix[p+1], rgba.Pix[p+2])
+			im.Y[im.YOffset(x, y)] = yy
+			if x%2 == 0 && y%2 == 0 {
+				c := im.COffset(x, y)
+				im.Cb[c], im.Cr[c] = cb, cr
+			}
+		}
+	}
+}
+
+// packed422ToI420 keeps every luma sample and averages the chroma of each
+// pair of lines.
+func packed422ToI420(frame []byte, width, height, stride int, l packed422, im *image.YCbCr) {
+
+	for y := 0; y < height; y++ {
+
+		row := frame[y*stride:]
+
+		for x := 0; x < width; x++ {
+			i := (x / 2) * 4
+			ys := row[i+l.y0]
+			if x%2 == 1 {
+				ys = row[i+l.y1]
+			}
+			im.Y[y*im.YStride+x] = ys
+		}
+	}
+
+	for y := 0; y < height; y += 2 {
+
+		a := frame[y*stride:]
+		b := a
+		if y+1 < height {
+			b = frame[(y+1)*stride:]
+		}
+
+		for x := 0; x < width; x += 2 {
+			i := (x / 2) * 4
+			c := (y/2)*im.CStride + x/2
+			im.Cb[c] = uint8((int(a[i+l.cb]) + int(b[i+l.cb]) + 1) / 2)
+			im.Cr[c] = uint8((int(a[i+l.cr]) + int(b[i+l.cr]) + 1) / 2)
+		}
+	}
+
+}
@@ -0,0 +1,169 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+const (
+	V4L2_INPUT_TYPE_TUNER  uint32 = 1
+	V4L2_INPUT_TYPE_CAMERA        = 2
+	V4L2_INPUT_TYPE_TOUCH         = 3
+
+	V4L2_IN_ST_NO_POWER    uint32 = 0x00000001
+	V4L2_IN_ST_NO_SIGNAL          = 0x00000002
+	V4L2_IN_ST_NO_COLOR           = 0x00000004
+	V4L2_IN_ST_HFLIP              = 0x00000010
+	V4L2_IN_ST_VFLIP              = 0x00000020
+	V4L2_IN_ST_NO_H_LOCK          = 0x00000100
+	V4L2_IN_ST_COLOR_KILL         = 0x00000200
+	V4L2_IN_ST_NO_V_LOCK          = 0x00000400
+	V4L2_IN_ST_NO_STD_LOCK        = 0x00000800
+	V4L2_IN_ST_NO_SYNC            = 0x00010000
+	V4L2_IN_ST_NO_EQU             = 0x00020000
+	V4L2_IN_ST_NO_CARRIER         = 0x00040000
+	V4L2_IN_ST_MACROVISION        = 0x01000000
+	V4L2_IN_ST_NO_ACCESS          = 0x02000000
+	V4L2_IN_ST_VTR                = 0x04000000
+
+	VIDIOC_ENUMINPUT uintptr = 0xC050561A
+	VIDIOC_G_INPUT           = 0x80045626
+)
+
+type v4l2_input struct {
+	Index        uint32
+	Name         [32]uint8
+	Type         uint32
+	Audioset     uint32
+	Tuner        uint32
+	Std          uint64
+	Status       uint32
+	Capabilities uint32
+	Reserved     [3]uint32
+	_            uint32 // std aligns the struct to 8 bytes
+}
+
+// Input is a video input of the device, such as a camera sensor or one of
+// the connectors of a capture card.
+type Input struct {
+	Index  int
+	Name   string
+	Type   uint32
+	Status SignalStatus
+}
+
+// SignalStatus decodes the V4L2_IN_ST status bits of an input. Drivers
+// only report what their hardware can detect, so a clear bit is not proof
+// the condition is absent; many cameras report nothing at all.
+type SignalStatus struct {
+	Flags uint32
+
+	NoPower   bool
+	NoSignal  bool
+	NoColor   bool
+	NoHLock   bool
+	NoVLock   bool
+	NoStdLock bool
+	NoSync    bool
+	NoCarrier bool
+	NoAccess  bool
+}
+
+func signalStatus(flags uint32) SignalStatus {
+	return SignalStatus{
+		Flags:     flags,
+		NoPower:   flags&V4L2_IN_ST_NO_POWER != 0,
+		NoSignal:  flags&V4L2_IN_ST_NO_SIGNAL != 0,
+		NoColor:   flags&V4L2_IN_ST_NO_COLOR != 0,
+		NoHLock:   flags&V4L2_IN_ST_NO_H_LOCK != 0,
+		NoVLock:   flags&V4L2_IN_ST_NO_V_LOCK != 0,
+		NoStdLock: flags&V4L2_IN_ST_NO_STD_LOCK != 0,
+		NoSync:    flags&V4L2_IN_ST_NO_SYNC != 0,
+		NoCarrier: flags&V4L2_IN_ST_NO_CARRIER != 0,
+		NoAccess:  flags&V4L2_IN_ST_NO_ACCESS != 0,
+	}
+}
+
+// HasSignal reports whether nothing indicates that the input lacks a
+// usable signal, so that a "No Signal" screen can be shown instead of
+// capturing black frames.
+func (s SignalStatus) HasSignal() bool {
+	return !(s.NoPower || s.NoSignal || s.NoHLock || s.NoVLock || s.NoSync || s.NoCarrier || s.NoAccess)
+}
+
+// Inputs enumerates the device's video inputs with their current status.
+func (dev *handle) Inputs() ([]Input, error) {
+
+	var inputs []Input
+
+	for i := uint32(0); ; i++ {
+
+		in, err := dev.enumInput(i)
+		if errors.Is(err, syscall.EINVAL) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to enumerate inputs: %v", err.Error())
+		}
+
+		inputs = append(inputs, in)
+	}
+
+	return inputs, nil
+}
+
+// CurrentInput returns the input the device captures from.
+func (dev *handle) CurrentInput() (Input, error) {
+
+	b := toBytes(uint32(0))
+
+	if err := dev.ioctl(VIDIOC_G_INPUT, toUintptr(b)); err != nil {
+		return Input{}, fmt.Errorf("Failed to get input: %v", err.Error())
+	}
+
+	var index uint32
+	if err := fromBytes(b, &index); err != nil {
+		return Input{}, err
+	}
+
+	in, err := dev.enumInput(index)
+	if err != nil {
+		return Input{}, fmt.Errorf("Failed to query input: %v", err.Error())
+	}
+
+	return in, nil
+}
+
+// SignalStatus reports the signal status of the current input, which the
+// driver samples when asked.
+func (dev *handle) SignalStatus() (SignalStatus, error) {
+
+	in, err := dev.CurrentInput()
+	if err != nil {
+		return SignalStatus{}, err
+	}
+
+	return in.Status, nil
+}
+
+func (dev *handle) enumInput(index uint32) (Input, error) {
+
+	in := v4l2_input{Index: index}
+	b := toBytes(in)
+
+	if err := dev.ioctl(VIDIOC_ENUMINPUT, toUintptr(b)); err != nil {
+		return Input{}, err
+	}
+
+	if err := fromBytes(b, &in); err != nil {
+		return Input{}, err
+	}
+
+	return Input{
+		Index:  int(in.Index),
+		Name:   cString(in.Name[:]),
+		Type:   in.Type,
+		Status: signalStatus(in.Status),
+	}, nil
+}
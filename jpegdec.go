@@ -0,0 +1,491 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+const (
+	V4L2_BUF_TYPE_VIDEO_OUTPUT         uint32 = 2
+	V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE        = 9
+	V4L2_MEMORY_MMAP                          = 1
+
+	VIDIOC_QUERYBUF uintptr = 0xC0585609
+)
+
+var V4L2_PIX_FMT_JPEG = FourCC("JPEG")
+
+// jpegDecoder decodes JPEG frames on a memory-to-memory V4L2 device, such
+// as the hardware JPEG decoder of many SoCs: each frame is queued on the
+// device's output queue and comes back decoded on its capture queue.
+// Single and multi-planar devices are driven alike, with USERPTR buffers
+// where the device takes them and mapped ones otherwise.
+type jpegDecoder struct {
+	handle
+	mu            sync.Mutex
+	mplane        bool
+	src, dst      jpegQueue
+	format        Format
+	width, height int
+	stride        int
+}
+
+// jpegQueue is one of a decoder's queues and its only buffer: the decoded
+// frame on the capture queue, and on the output queue, for MMAP, the
+// buffer JPEG frames are copied into. USERPTR output buffers are the
+// frames themselves.
+type jpegQueue struct {
+	typ    uint32
+	memory uint32
+	buf    []byte
+	mapped bool
+}
+
+// WithHardwareJPEG decodes V4L2_PIX_FMT_MJPEG frames for GetFrame on a
+// memory-to-memory JPEG decoder device when one is found, instead of with
+// image/jpeg. Without such a device, or if it fails on a frame, frames are
+// decoded in software as usual. Devices that only take MMAP buffers need a
+// backend implementing MmapBackend, as the default does, and cost a copy
+// of each JPEG frame.
+func WithHardwareJPEG() Option {
+	return func(c *config) {
+		c.hardwareJPEG = true
+	}
+}
+
+// findJPEGDecoder opens the first memory-to-memory device that decodes
+// JPEG into a format with a converter at width x height, or returns nil.
+func findJPEGDecoder(width, height int, config config) *jpegDecoder {
+
+	nodes, _ := filepath.Glob("/dev/video*")
+
+	for _, node := range nodes {
+		if d, err := openJPEGDecoder(node, width, height, config); err == nil {
+			return d
+		}
+	}
+
+	return nil
+}
+
+func openJPEGDecoder(device string, width, height int, config config) (*jpegDecoder, error) {
+
+	sys := config.backend
+
+	fd, err := sys.Open(device, os.O_RDWR|syscall.O_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &jpegDecoder{handle: handle{device: device, fd: fd, sys: sys}}
+
+	if err := d.setup(width, height); err != nil {
+		d.close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *jpegDecoder) setup(width, height int) error {
+
+	caps, err := d.queryCap()
+	if err != nil {
+		return err
+	}
+
+	d.caps = caps
+
+	switch {
+	case d.hasCap(V4L2_CAP_VIDEO_M2M):
+		d.src.typ, d.dst.typ = V4L2_BUF_TYPE_VIDEO_OUTPUT, V4L2_BUF_TYPE_VIDEO_CAPTURE
+	case d.hasCap(V4L2_CAP_VIDEO_M2M_MPLANE):
+		d.mplane = true
+		d.src.typ, d.dst.typ = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE, V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE
+	default:
+		return errors.New("Not a memory-to-memory device")
+	}
+
+	in := V4L2_PIX_FMT_MJPEG
+	if !d.outputFormat(in) {
+		in = V4L2_PIX_FMT_JPEG
+		if !d.outputFormat(in) {
+			return errors.New("Device does not decode JPEG")
+		}
+	}
+
+	srcPix, err := d.setQueueFormat(d.src.typ, in, width, height, width*height*2)
+	if err != nil {
+		return fmt.Errorf("Failed to set output format: %v", err.Error())
+	}
+
+	pix, err := d.setQueueFormat(d.dst.typ, V4L2_PIX_FMT_YUYV, width, height, 0)
+	if err != nil {
+		return fmt.Errorf("Failed to set capture format: %v", err.Error())
+	}
+
+	// The decoder may offer another format than YUYV, which is as good
+	// as long as it can be converted.
+	d.format = Format(pix.Pixelformat)
+	d.width, d.height = int(pix.Width), int(pix.Height)
+	d.stride = int(pix.Bytesperline)
+	if line := d.width * bytesPerPixel(d.format); d.stride < line {
+		d.stride = line
+	}
+
+	if d.width != width || d.height != height || lookupConverter(d.format) == nil || d.format.Compressed() {
+		return fmt.Errorf("Decoder offers %v %dx%d", d.format, d.width, d.height)
+	}
+
+	if err := d.request(&d.src, int(srcPix.Sizeimage)); err != nil {
+		return fmt.Errorf("Failed to start output queue: %v", err.Error())
+	}
+
+	if err := d.request(&d.dst, int(pix.Sizeimage)); err != nil {
+		return fmt.Errorf("Failed to start capture queue: %v", err.Error())
+	}
+
+	return d.streamOn()
+}
+
+// outputFormat reports whether the device takes format on its output
+// queue.
+func (d *jpegDecoder) outputFormat(format Format) bool {
+
+	for i := uint32(0); ; i++ {
+
+		f := v4l2_fmtdesc{Index: i, Type: d.src.typ}
+		b := toBytes(f)
+
+		if err := d.ioctl(VIDIOC_ENUM_FMT, toUintptr(b)); err != nil {
+			return false
+		}
+
+		if err := fromBytes(b, &f); err != nil {
+			return false
+		}
+
+		if Format(f.Pixelformat) == format {
+			return true
+		}
+	}
+}
+
+// setQueueFormat sets the format of the queue of type typ and returns what
+// the driver settled on, as a single-plane format. Multi-planar formats of
+// more than one plane are refused.
+func (d *jpegDecoder) setQueueFormat(typ uint32, format Format, width, height, size int) (v4l2_pix_format, error) {
+
+	if !d.mplane {
+
+		f := v4l2_format{
+			Type: typ,
+			Pix: v4l2_pix_format{
+				Width:       uint32(width),
+				Height:      uint32(height),
+				Pixelformat: uint32(format),
+				Sizeimage:   uint32(size),
+			},
+		}
+
+		b := toBytes(f)
+
+		if err := d.ioctl(VIDIOC_S_FMT, toUintptr(b)); err != nil {
+			return f.Pix, err
+		}
+
+		if err := fromBytes(b, &f); err != nil {
+			return f.Pix, err
+		}
+
+		return f.Pix, nil
+	}
+
+	f := v4l2_format_mplane{
+		Type: typ,
+		PixMp: v4l2_pix_format_mplane{
+			Width:       uint32(width),
+			Height:      uint32(height),
+			Pixelformat: uint32(format),
+			NumPlanes:   1,
+		},
+	}
+	f.PixMp.PlaneFmt[0].Sizeimage = uint32(size)
+
+	b := toBytes(f)
+
+	if err := d.ioctl(VIDIOC_S_FMT, toUintptr(b)); err != nil {
+		return v4l2_pix_format{}, err
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return v4l2_pix_format{}, err
+	}
+
+	if f.PixMp.NumPlanes != 1 {
+		return v4l2_pix_format{}, fmt.Errorf("Driver set %d planes", f.PixMp.NumPlanes)
+	}
+
+	return v4l2_pix_format{
+		Width:        f.PixMp.Width,
+		Height:       f.PixMp.Height,
+		Pixelformat:  f.PixMp.Pixelformat,
+		Field:        f.PixMp.Field,
+		Bytesperline: f.PixMp.PlaneFmt[0].Bytesperline,
+		Sizeimage:    f.PixMp.PlaneFmt[0].Sizeimage,
+	}, nil
+}
+
+// request sets q up with one buffer of size bytes, USERPTR if the device
+// takes it and otherwise mapped from the device.
+func (d *jpegDecoder) request(q *jpegQueue, size int) error {
+
+	memory := []uint32{V4L2_MEMORY_USERPTR}
+	if _, ok := d.sys.(MmapBackend); ok {
+		memory = append(memory, V4L2_MEMORY_MMAP)
+	}
+
+	var err error
+
+	for _, q.memory = range memory {
+
+		r := v4l2_requestbuffers{Count: 1, Type: q.typ, Memory: q.memory}
+		b := toBytes(r)
+
+		if err = d.ioctl(VIDIOC_REQBUFS, toUintptr(b)); err != nil {
+			continue
+		}
+
+		if err := fromBytes(b, &r); err != nil {
+			return err
+		}
+
+		if r.Count == 0 {
+			return errors.New("Driver granted no buffers")
+		}
+
+		if q.memory == V4L2_MEMORY_MMAP {
+			return d.mapBuffer(q)
+		}
+
+		if q.typ == d.dst.typ {
+			q.buf = frameBuffer(size)
+		}
+
+		return nil
+	}
+
+	return err
+}
+
+// mapBuffer maps q's buffer, which the driver allocated.
+func (d *jpegDecoder) mapBuffer(q *jpegQueue) error {
+
+	b, planes := d.buffer(q, nil, 0)
+
+	err := d.ioctl(VIDIOC_QUERYBUF, toUintptr(b))
+	runtime.KeepAlive(planes)
+
+	if err != nil {
+		return fmt.Errorf("Failed to query buffer: %v", err.Error())
+	}
+
+	offset, length, _, err := d.readBuffer(b, planes)
+	if err != nil {
+		return err
+	}
+
+	m, err := d.sys.(MmapBackend).Mmap(d.fd, int64(offset), length)
+	if err != nil {
+		return err
+	}
+
+	q.buf, q.mapped = m, true
+
+	return nil
+}
+
+// buffer lays out the v4l2_buffer for q's buffer to be queued with data,
+// used bytes of it, and for multi-planar devices the plane it points to,
+// which must be kept alive until the request returns. Only USERPTR
+// buffers point at data; mapped ones already hold it.
+func (d *jpegDecoder) buffer(q *jpegQueue, data []byte, used int) ([]byte, []byte) {
+
+	buf := v4l2_buffer{Type: q.typ, Memory: q.memory}
+
+	var addr uint64
+	if q.memory == V4L2_MEMORY_USERPTR && len(data) > 0 {
+		addr = uint64(toUintptr(data))
+	}
+
+	if !d.mplane {
+		buf.Userptr = addr
+		buf.Length = uint32(len(data))
+		buf.Bytesused = uint32(used)
+		return toBytes(buf), nil
+	}
+
+	planes := toBytes([]v4l2_plane{{Bytesused: uint32(used), Length: uint32(len(data)), M: addr}})
+
+	buf.Userptr = uint64(toUintptr(planes))
+	buf.Length = 1
+
+	return toBytes(buf), planes
+}
+
+// readBuffer decodes a buffer the driver filled in, returning the memory
+// offset of a mapped buffer, its length, the bytes used and its flags.
+func (d *jpegDecoder) readBuffer(b, planes []byte) (uint32, int, uint32, error) {
+
+	var buf v4l2_buffer
+	if err := fromBytes(b, &buf); err != nil {
+		return 0, 0, 0, fmt.Errorf("Failed to read buffer: %v", err.Error())
+	}
+
+	if !d.mplane {
+		return uint32(buf.Userptr), int(buf.Length), buf.Flags, nil
+	}
+
+	p := make([]v4l2_plane, 1)
+	if err := fromBytes(planes, &p); err != nil {
+		return 0, 0, 0, fmt.Errorf("Failed to read buffer: %v", err.Error())
+	}
+
+	return uint32(p[0].M), int(p[0].Length), buf.Flags, nil
+}
+
+// streamOn starts both queues.
+func (d *jpegDecoder) streamOn() error {
+
+	for _, typ := range []uint32{d.src.typ, d.dst.typ} {
+		if err := d.ioctl(VIDIOC_STREAMON, toUintptr(toBytes(typ))); err != nil {
+			return fmt.Errorf("Failed to start streaming: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// reset stops both queues, which takes back any buffer still queued, and
+// starts them again for the next frame.
+func (d *jpegDecoder) reset() {
+
+	for _, typ := range []uint32{d.src.typ, d.dst.typ} {
+		d.ioctl(VIDIOC_STREAMOFF, toUintptr(toBytes(typ)))
+	}
+
+	d.streamOn()
+}
+
+// queue queues q's buffer holding data, with its used bytes, or the whole
+// buffer for the capture queue.
+func (d *jpegDecoder) queue(q *jpegQueue, data []byte) error {
+
+	used := len(data)
+
+	if q.mapped && data != nil {
+		if len(data) > len(q.buf) {
+			return fmt.Errorf("Frame of %d bytes does not fit the %d byte buffer", len(data), len(q.buf))
+		}
+		copy(q.buf, data)
+	}
+
+	if data == nil || q.mapped {
+		data = q.buf
+	}
+
+	b, planes := d.buffer(q, data, used)
+
+	err := d.ioctl(VIDIOC_QBUF, toUintptr(b))
+	runtime.KeepAlive(planes)
+
+	return err
+}
+
+// dequeue waits for q's buffer and returns its flags.
+func (d *jpegDecoder) dequeue(q *jpegQueue) (uint32, error) {
+
+	b, planes := d.buffer(q, nil, 0)
+
+	err := d.ioctl(VIDIOC_DQBUF, toUintptr(b))
+	runtime.KeepAlive(planes)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, _, flags, err := d.readBuffer(b, planes)
+
+	return flags, err
+}
+
+// decode runs one JPEG frame through the device and returns the decoded
+// frame, which is only valid until the next call. A frame the device fails
+// on is taken back from it, so that the next one starts afresh.
+func (d *jpegDecoder) decode(frame []byte) ([]byte, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.queue(&d.dst, nil); err != nil {
+		return nil, fmt.Errorf("Failed to qbuf decoded frame: %w", err)
+	}
+
+	if err := d.queue(&d.src, frame); err != nil {
+		d.reset()
+		return nil, fmt.Errorf("Failed to qbuf JPEG frame: %w", err)
+	}
+
+	if _, err := d.dequeue(&d.src); err != nil {
+		d.reset()
+		return nil, fmt.Errorf("Failed to dqbuf JPEG frame: %w", err)
+	}
+
+	flags, err := d.dequeue(&d.dst)
+	if err != nil {
+		d.reset()
+		return nil, fmt.Errorf("Failed to dqbuf decoded frame: %w", err)
+	}
+
+	if flags&V4L2_BUF_FLAG_ERROR != 0 {
+		return nil, errors.New("Decoder flagged the frame as corrupt")
+	}
+
+	return d.dst.buf, nil
+}
+
+// converter decodes frames on the device and converts the result,
+// falling back to software for frames the device fails on or that no
+// longer have the size it was set up for.
+func (d *jpegDecoder) converter(software Converter) Converter {
+	return func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+
+		if width == d.width && height == d.height {
+			if out, err := d.decode(jpegFrame(src)); err == nil {
+				lookupConverter(d.format)(out, d.width, d.height, d.stride, scale, dst)
+				return
+			}
+		}
+
+		software(src, width, height, stride, scale, dst)
+	}
+}
+
+func (d *jpegDecoder) close() {
+
+	d.sys.Close(d.fd)
+
+	if m, ok := d.sys.(MmapBackend); ok {
+		for _, q := range []*jpegQueue{&d.src, &d.dst} {
+			if q.mapped {
+				m.Munmap(q.buf)
+			}
+		}
+	}
+}
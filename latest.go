@@ -0,0 +1,117 @@
+package v4l
+
+import (
+	"image"
+	"sync"
+)
+
+type latestFrame struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	im   *image.RGBA
+	err  error
+	stop chan struct{}
+	done chan struct{}
+}
+
+// LatestFrame returns the most recently captured frame. The first call
+// starts a goroutine that captures continuously until Close, and waits for
+// its first frame; later calls return immediately.
+func (dev *Device) LatestFrame() (*image.RGBA, error) {
+
+	dev.latestOnce.Do(func() {
+		l := &latestFrame{
+			stop: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		l.cond = sync.NewCond(&l.mu)
+		dev.mu.Lock()
+		dev.latest = l
+		dev.mu.Unlock()
+		dev.beginStream()
+		go l.run(dev)
+	})
+
+	dev.mu.Lock()
+	l := dev.latest
+	dev.mu.Unlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.im == nil && l.err == nil {
+		l.cond.Wait()
+	}
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.im, nil
+}
+
+func (l *latestFrame) run(dev *Device) {
+
+	defer close(l.done)
+	defer dev.endStream()
+
+	for {
+
+		select {
+		case <-l.stop:
+			l.closed()
+			return
+		default:
+		}
+
+		im, err := dev.GetFrame()
+
+		// A capture failing because close stopped streaming ends the
+		// goroutine without replacing the last frame.
+		select {
+		case <-l.stop:
+			l.closed()
+			return
+		default:
+		}
+
+		l.mu.Lock()
+		if err != nil {
+			l.err = err
+		} else {
+			l.im = im
+		}
+		l.cond.Broadcast()
+		l.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// close stops the goroutine and waits for it. A goroutine blocked on a
+// frame that never comes would keep close waiting forever, so streaming is
+// stopped first, which makes the driver hand the buffer back.
+func (l *latestFrame) close(dev *Device) {
+
+	close(l.stop)
+
+	if !dev.readIO && !dev.config.noStreamOn {
+		dev.ioctl(VIDIOC_STREAMOFF, toUintptr(toBytes(dev.bufType)))
+	}
+
+	<-l.done
+
+	l.closed()
+}
+
+// closed fails the callers waiting for a frame, and those to come, once the
+// goroutine has stopped.
+func (l *latestFrame) closed() {
+
+	l.mu.Lock()
+	l.err = ErrDeviceClosed
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
@@ -0,0 +1,42 @@
+package v4l
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockDevice takes the advisory lock WithExclusiveLock coordinates on. Links
+// such as /dev/v4l/by-id entries are resolved first so every name of a
+// device shares one lock. Closing the returned file releases it.
+func lockDevice(device string) (*os.File, error) {
+
+	if p, err := filepath.EvalSymlinks(device); err == nil {
+		device = p
+	}
+
+	// /run is writable only by root, so the lock lives in the user's
+	// runtime directory, or the temporary directory without one.
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("v4l-%s.lock", filepath.Base(device)))
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open lock file: %v", err.Error())
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrDeviceBusy
+		}
+		return nil, fmt.Errorf("Failed to lock device: %v", err.Error())
+	}
+
+	return f, nil
+}
@@ -0,0 +1,21 @@
+package v4l
+
+// Logger receives the package's diagnostic output, such as every ioctl
+// the driver fails. *log.Logger implements it, and structured loggers
+// take a small adapter.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// SetLogger routes the device's diagnostics to l. Without a logger, the
+// default, nothing is logged.
+func (dev *handle) SetLogger(l Logger) {
+	dev.logger = l
+}
+
+func (dev *handle) logf(format string, args ...interface{}) {
+
+	if dev.logger != nil {
+		dev.logger.Printf(format, args...)
+	}
+}
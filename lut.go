@@ -0,0 +1,72 @@
+package v4l
+
+import (
+	"image"
+	"math"
+)
+
+// SetLUT makes GetFrame map every red, green and blue sample through lut
+// as frames are converted, as a software stand-in for brightness or gamma
+// controls the camera lacks or keeps in auto mode. Packed 4:2:2 and NV12
+// samples are mapped as they are converted; other formats are mapped a
+// band of a parallel conversion at a time, as soon as it is converted. A
+// nil lut, the default, leaves the conversion untouched.
+func (dev *Device) SetLUT(lut *[256]uint8) {
+	dev.lut = lut
+}
+
+// GammaLUT builds a lookup table for SetLUT that applies gamma to the
+// normalized samples. Values below 1 brighten the midtones, above 1 darken
+// them.
+func GammaLUT(gamma float64) *[256]uint8 {
+
+	var lut [256]uint8
+
+	for i := range lut {
+		lut[i] = clampUint8(255 * math.Pow(float64(i)/255, gamma))
+	}
+
+	return &lut
+}
+
+// BrightnessContrastLUT builds a lookup table for SetLUT that scales the
+// samples around mid grey by contrast and then adds brightness. A contrast
+// of 1 and brightness of 0 leave them unchanged.
+func BrightnessContrastLUT(brightness int, contrast float64) *[256]uint8 {
+
+	var lut [256]uint8
+
+	for i := range lut {
+		lut[i] = clampUint8((float64(i)-128)*contrast + 128 + float64(brightness))
+	}
+
+	return &lut
+}
+
+func clampUint8(v float64) uint8 {
+
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+
+	return uint8(v + 0.5)
+}
+
+// applyLUT maps the color samples of im through lut, leaving alpha alone.
+func applyLUT(im *image.RGBA, lut *[256]uint8) {
+
+	b := im.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+
+		row := im.Pix[im.PixOffset(b.Min.X, y):]
+
+		for i := 0; i < b.Dx()*4; i += 4 {
+			row[i+0] = lut[row[i+0]]
+			row[i+1] = lut[row[i+1]]
+			row[i+2] = lut[row[i+2]]
+		}
+	}
+}
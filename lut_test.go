@@ -0,0 +1,44 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestFusedLUT(t *testing.T) {
+
+	const width, height = 8, 4
+
+	lut := GammaLUT(0.5)
+
+	tests := []struct {
+		format Format
+		stride int
+		size   int
+	}{
+		{V4L2_PIX_FMT_YUYV, width * 2, width * 2 * height},
+		{V4L2_PIX_FMT_UYVY, width * 2, width * 2 * height},
+		{V4L2_PIX_FMT_NV12, width, width * height * 3 / 2},
+		{V4L2_PIX_FMT_NV21, width, width * height * 3 / 2},
+	}
+
+	for _, tt := range tests {
+
+		src := make([]byte, tt.size)
+		for i := range src {
+			src[i] = uint8(i * 37)
+		}
+
+		want := image.NewRGBA(image.Rect(0, 0, width, height))
+		lookupConverter(tt.format)(src, width, height, tt.stride, 1, want)
+		applyLUT(want, lut)
+
+		got := image.NewRGBA(want.Rect)
+		lookupLUTConverter(tt.format, lut)(src, width, height, tt.stride, 1, got)
+
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%v: fused LUT differs from a second pass", tt.format)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DeviceConfig describes one device for a Manager to open.
+type DeviceConfig struct {
+	Path          string
+	Format        Format
+	Width, Height int
+}
+
+// Manager coordinates a fixed set of devices, such as a stereo pair, that
+// are captured together.
+type Manager struct {
+	configs []DeviceConfig
+	opts    []Option
+	devices []*Device
+}
+
+// ManagerError holds the per-device failures of a Manager operation, keyed
+// by the device's index in the configuration.
+type ManagerError map[int]error
+
+func (e ManagerError) Error() string {
+
+	var idx []int
+	for i := range e {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	var msgs []string
+	for _, i := range idx {
+		msgs = append(msgs, fmt.Sprintf("device %d: %v", i, e[i]))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// NewManager opens every configured device. A zero Format means
+// V4L2_PIX_FMT_YUYV.
+func NewManager(configs []DeviceConfig, opts ...Option) (*Manager, error) {
+
+	m := &Manager{
+		configs: configs,
+		opts:    opts,
+		devices: make([]*Device, len(configs)),
+	}
+
+	for i := range configs {
+
+		dev, err := m.open(i)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("Failed to open %s: %w", configs[i].Path, err)
+		}
+
+		m.devices[i] = dev
+	}
+
+	return m, nil
+}
+
+func (m *Manager) open(i int) (*Device, error) {
+
+	c := m.configs[i]
+
+	format := c.Format
+	if format == 0 {
+		format = V4L2_PIX_FMT_YUYV
+	}
+
+	return OpenFormat(c.Path, format, c.Width, c.Height, m.opts...)
+}
+
+// Devices returns the managed devices in configuration order. An entry is
+// nil while its device is waiting to be reconnected.
+func (m *Manager) Devices() []*Device {
+	return m.devices
+}
+
+// CaptureAll captures one frame from every device. The captures are
+// released together from a barrier so the frames are as close in time as
+// the devices allow. A device that fails is closed and reopened on the next
+// call; its frame is nil and its error is reported in a ManagerError.
+func (m *Manager) CaptureAll() ([]*image.RGBA, error) {
+
+	errs := ManagerError{}
+
+	for i, dev := range m.devices {
+
+		if dev != nil {
+			continue
+		}
+
+		dev, err := m.open(i)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		m.devices[i] = dev
+	}
+
+	frames := make([]*image.RGBA, len(m.devices))
+	failed := make([]error, len(m.devices))
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+
+	for i, dev := range m.devices {
+
+		if dev == nil {
+			continue
+		}
+
+		ready.Add(1)
+		done.Add(1)
+
+		go func(i int, dev *Device) {
+			defer done.Done()
+
+			ready.Done()
+			<-start
+
+			frames[i], failed[i] = dev.GetFrame()
+		}(i, dev)
+	}
+
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	for i, err := range failed {
+
+		if err == nil {
+			continue
+		}
+
+		errs[i] = err
+
+		m.devices[i].Close()
+		m.devices[i] = nil
+	}
+
+	if len(errs) > 0 {
+		return frames, errs
+	}
+
+	return frames, nil
+}
+
+func (m *Manager) Close() {
+
+	for i, dev := range m.devices {
+		if dev != nil {
+			dev.Close()
+			m.devices[i] = nil
+		}
+	}
+}
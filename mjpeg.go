@@ -0,0 +1,147 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// convertMJPEG decodes a frame with image/jpeg. Frames cut short are
+// flagged corrupt as they are captured, see finishFrame, so they count in
+// Stats.FramesWithError and fail with ErrFrameCorrupt under SetStrict; a
+// frame that still fails to decode leaves dst as it was.
+func convertMJPEG(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+
+	im, err := jpeg.Decode(bytes.NewReader(jpegFrame(src)))
+	if err != nil {
+		return
+	}
+
+	b := dst.Bounds()
+	scale = sampleStep(width, height, scale, dst)
+	r := im.Bounds()
+
+	yc, fast := im.(*image.YCbCr)
+
+	for y := 0; y < b.Dy() && y*scale < r.Dy(); y++ {
+
+		p := dst.PixOffset(b.Min.X, b.Min.Y+y)
+
+		for x := 0; x < b.Dx() && x*scale < r.Dx(); x++ {
+
+			sx, sy := r.Min.X+x*scale, r.Min.Y+y*scale
+
+			if fast {
+				yy := yc.Y[yc.YOffset(sx, sy)]
+				c := yc.COffset(sx, sy)
+				dst.Pix[p+0], dst.Pix[p+1], dst.Pix[p+2] = color.YCbCrToRGB(yy, yc.Cb[c], yc.Cr[c])
+			} else {
+				c := color.RGBAModel.Convert(im.At(sx, sy)).(color.RGBA)
+				dst.Pix[p+0], dst.Pix[p+1], dst.Pix[p+2] = c.R, c.G, c.B
+			}
+			dst.Pix[p+3] = 0xff
+
+			p += 4
+		}
+	}
+}
+
+// jpegFrame trims the zeros the capture buffer holds after a JPEG image,
+// up to its end of image marker, and adds the Huffman tables that MJPEG
+// frames leave out.
+func jpegFrame(frame []byte) []byte {
+
+	if i := bytes.LastIndex(frame, []byte{0xff, 0xd9}); i >= 0 {
+		frame = frame[:i+2]
+	}
+
+	return withHuffmanTables(frame)
+}
+
+// withHuffmanTables splices defaultDHT in before the first scan of a JPEG
+// image that defines no Huffman tables, as the MJPEG format allows since
+// its tables are fixed.
+func withHuffmanTables(frame []byte) []byte {
+
+	if len(frame) < 4 || frame[0] != 0xff || frame[1] != 0xd8 {
+		return frame
+	}
+
+	for i := 2; i+4 <= len(frame); {
+
+		if frame[i] != 0xff {
+			return frame
+		}
+
+		switch frame[i+1] {
+		case 0xff:
+			// Fill byte before a marker.
+			i++
+			continue
+		case 0xc4:
+			return frame
+		case 0xda:
+			out := make([]byte, 0, len(frame)+len(defaultDHT))
+			out = append(out, frame[:i]...)
+			out = append(out, defaultDHT...)
+			return append(out, frame[i:]...)
+		}
+
+		i += 2 + (int(frame[i+2])<<8 | int(frame[i+3]))
+	}
+
+	return frame
+}
+
+// jpegComplete reports whether frame holds a whole JPEG image, from its
+// start to its end of image marker.
+func jpegComplete(frame []byte, info FrameInfo) bool {
+
+	if n := int(info.Bytesused); n > 0 && n < len(frame) {
+		frame = frame[:n]
+	}
+
+	return len(frame) >= 4 && frame[0] == 0xff && frame[1] == 0xd8 &&
+		bytes.LastIndex(frame, []byte{0xff, 0xd9}) >= 0
+}
+
+// defaultDHT is the DHT segment of the Huffman tables in section K.3 of the
+// JPEG standard, which MJPEG implies.
+var defaultDHT = []byte{
+	0xff, 0xc4, 0x01, 0xa2, 0x00, 0x00, 0x01, 0x05, 0x01, 0x01, 0x01, 0x01,
+	0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02,
+	0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x10, 0x00, 0x02,
+	0x01, 0x03, 0x03, 0x02, 0x04, 0x03, 0x05, 0x05, 0x04, 0x04, 0x00, 0x00,
+	0x01, 0x7d, 0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12, 0x21, 0x31,
+	0x41, 0x06, 0x13, 0x51, 0x61, 0x07, 0x22, 0x71, 0x14, 0x32, 0x81, 0x91,
+	0xa1, 0x08, 0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0, 0x24, 0x33,
+	0x62, 0x72, 0x82, 0x09, 0x0a, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x25, 0x26,
+	0x27, 0x28, 0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x43,
+	0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57,
+	0x58, 0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x73,
+	0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7a, 0x83, 0x84, 0x85, 0x86, 0x87,
+	0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98, 0x99, 0x9a,
+	0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+	0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7,
+	0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+	0xe1, 0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea, 0xf1, 0xf2,
+	0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8, 0xf9, 0xfa, 0x01, 0x00, 0x03, 0x01,
+	0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+	0x0b, 0x11, 0x00, 0x02, 0x01, 0x02, 0x04, 0x04, 0x03, 0x04, 0x07, 0x05,
+	0x04, 0x04, 0x00, 0x01, 0x02, 0x77, 0x00, 0x01, 0x02, 0x03, 0x11, 0x04,
+	0x05, 0x21, 0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71, 0x13, 0x22,
+	0x32, 0x81, 0x08, 0x14, 0x42, 0x91, 0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33,
+	0x52, 0xf0, 0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34, 0xe1, 0x25,
+	0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26, 0x27, 0x28, 0x29, 0x2a, 0x35, 0x36,
+	0x37, 0x38, 0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a,
+	0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5a, 0x63, 0x64, 0x65, 0x66,
+	0x67, 0x68, 0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7a,
+	0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x92, 0x93, 0x94,
+	0x95, 0x96, 0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+	0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba,
+	0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+	0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7,
+	0xe8, 0xe9, 0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8, 0xf9, 0xfa,
+}
@@ -0,0 +1,65 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// stripDHT removes the Huffman table segments from a JPEG image, as MJPEG
+// cameras send it.
+func stripDHT(t *testing.T, data []byte) []byte {
+
+	out := append([]byte(nil), data[:2]...)
+
+	for i := 2; i+4 <= len(data); {
+		n := 2 + (int(data[i+2])<<8 | int(data[i+3]))
+		if data[i+1] == 0xda {
+			return append(out, data[i:]...)
+		}
+		if data[i+1] != 0xc4 {
+			out = append(out, data[i:i+n]...)
+		}
+		i += n
+	}
+
+	t.Fatal("no scan in the image")
+	return nil
+}
+
+func TestJPEGFrameDefaultHuffmanTables(t *testing.T) {
+
+	im := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for i := range im.Pix {
+		im.Pix[i] = uint8(i * 7)
+	}
+
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, im, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := stripDHT(t, b.Bytes())
+	if bytes.Contains(frame, []byte{0xff, 0xc4}) {
+		t.Fatal("DHT segment left in the frame")
+	}
+
+	// The capture buffer holds zeros after the image.
+	frame = append(frame, make([]byte, 64)...)
+
+	out, err := jpeg.Decode(bytes.NewReader(jpegFrame(frame)))
+	if err != nil {
+		t.Fatalf("decoding the frame: %v", err)
+	}
+	if out.Bounds() != im.Bounds() {
+		t.Errorf("bounds = %v, want %v", out.Bounds(), im.Bounds())
+	}
+
+	if !jpegComplete(frame, FrameInfo{}) {
+		t.Error("jpegComplete = false for a whole frame")
+	}
+	if jpegComplete(frame[:len(frame)/2], FrameInfo{}) {
+		t.Error("jpegComplete = true for a truncated frame")
+	}
+}
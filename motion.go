@@ -0,0 +1,207 @@
+package v4l
+
+import (
+	"image"
+)
+
+// motionStep is the spacing of the luma samples a MotionDetector compares,
+// in pixels both ways. Every other pixel of every other line is plenty to
+// notice motion and keeps the comparison at a quarter of the frame.
+const motionStep = 2
+
+// MotionDetector detects motion by differencing consecutive frames. A
+// sample counts as changed when its luma moved by more than Floor, which
+// keeps sensor noise from counting, and a frame shows motion when the
+// fraction of changed samples exceeds Level. A MotionDetector remembers the
+// last frame it saw, so it must not be shared between cameras or used from
+// several goroutines at once.
+type MotionDetector struct {
+	Floor uint8
+	Level float64
+
+	prev   []uint8
+	cur    []uint8
+	width  int
+	height int
+	change float64
+}
+
+// NewMotionDetector returns a MotionDetector with the given noise floor and
+// level, for example 16 and 0.01 to trigger when a hundredth of the frame
+// changes by more than 16 levels.
+func NewMotionDetector(floor uint8, level float64) *MotionDetector {
+	return &MotionDetector{Floor: floor, Level: level}
+}
+
+// Detect compares im with the previous frame and reports whether it shows
+// motion. The first frame, and the first after the size changes, only
+// becomes the reference and never shows motion.
+func (d *MotionDetector) Detect(im *image.RGBA) bool {
+
+	b := im.Bounds()
+	cur := d.samples(b.Dx(), b.Dy())
+
+	i := 0
+	for y := 0; y < b.Dy(); y += motionStep {
+		p := im.Pix[im.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x := 0; x < b.Dx(); x += motionStep {
+			cur[i] = rgbLuma(p[x*4], p[x*4+1], p[x*4+2])
+			i++
+		}
+	}
+
+	return d.compare(cur)
+}
+
+// DetectFrame is Detect for a raw frame as GetRawFrame returns it. Only
+// the luma plane of packed 4:2:2 and NV12 frames is read, which is far
+// cheaper than converting the frame first. ok is false for other formats.
+func (d *MotionDetector) DetectFrame(frame []byte, format Format, width, height, stride int) (moved, ok bool) {
+
+	if !motionFormat(format) {
+		return false, false
+	}
+
+	if stride*height > len(frame) {
+		return false, true
+	}
+
+	cur := d.samples(width, height)
+
+	i := 0
+	if l, ok := packed422Layouts[format]; ok {
+		for y := 0; y < height; y += motionStep {
+			row := frame[y*stride:]
+			for x := 0; x < width; x += motionStep {
+				// Pixel x is the first luma sample of macropixel x/2.
+				cur[i] = row[x*2+l.y0]
+				i++
+			}
+		}
+	} else {
+		for y := 0; y < height; y += motionStep {
+			row := frame[y*stride:]
+			for x := 0; x < width; x += motionStep {
+				cur[i] = row[x]
+				i++
+			}
+		}
+	}
+
+	return d.compare(cur), true
+}
+
+// Change returns the fraction of samples that changed in the last frame
+// compared.
+func (d *MotionDetector) Change() float64 {
+	return d.change
+}
+
+// Reset forgets the reference frame, so the next frame only becomes the
+// new reference.
+func (d *MotionDetector) Reset() {
+	d.prev = nil
+	d.change = 0
+}
+
+// samples returns the buffer for the current frame's samples, dropping the
+// reference when the size changed.
+func (d *MotionDetector) samples(width, height int) []uint8 {
+
+	if width != d.width || height != d.height {
+		d.width, d.height = width, height
+		d.prev = nil
+	}
+
+	n := ((width + motionStep - 1) / motionStep) * ((height + motionStep - 1) / motionStep)
+	if len(d.cur) != n {
+		d.cur = make([]uint8, n)
+	}
+
+	return d.cur
+}
+
+func (d *MotionDetector) compare(cur []uint8) bool {
+
+	prev := d.prev
+	d.prev, d.cur = cur, prev
+
+	if len(prev) != len(cur) || len(cur) == 0 {
+		d.change = 0
+		return false
+	}
+
+	changed := 0
+	for i, v := range cur {
+		diff := int(v) - int(prev[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > int(d.Floor) {
+			changed++
+		}
+	}
+
+	d.change = float64(changed) / float64(len(cur))
+
+	return d.change > d.Level
+}
+
+// motionFormat reports whether DetectFrame can read the luma of format.
+func motionFormat(format Format) bool {
+
+	if _, ok := packed422Layouts[format]; ok {
+		return true
+	}
+
+	return semiPlanar(format)
+}
+
+// rgbLuma approximates BT.601 luma in fixed point.
+func rgbLuma(r, g, b uint8) uint8 {
+	return uint8((77*uint32(r) + 150*uint32(g) + 29*uint32(b)) >> 8)
+}
+
+// motionFrame captures a frame for a stream filtered by d and reports
+// whether it shows motion. Frames the detector can read raw are only
+// converted when they do, so still scenes cost little more than the
+// capture itself. With skipErrors, frames the driver flagged fail with
+// ErrFrameCorrupt before they reach the detector.
+func (dev *Device) motionFrame(d *MotionDetector, skipErrors bool) (*image.RGBA, bool, error) {
+
+	dev.mu.Lock()
+	bg := dev.background
+	dev.mu.Unlock()
+
+	if bg != nil || !motionFormat(dev.format) {
+		im, info, err := dev.getFrame()
+		if err == nil && skipErrors && info.Error() {
+			err = ErrFrameCorrupt
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return im, d.Detect(im), nil
+	}
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, false, err
+	}
+
+	frame, info, err := dev.readFrame()
+	if err == nil && skipErrors && info.Error() {
+		err = ErrFrameCorrupt
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if moved, _ := d.DetectFrame(frame, dev.format, dev.width, dev.height, dev.stride()); !moved {
+		return nil, false, nil
+	}
+
+	im := image.NewRGBA(dev.bounds())
+	dev.convert(frame, im)
+
+	return dev.transformed(im, info), true, nil
+}
@@ -0,0 +1,188 @@
+package v4l
+
+import (
+	"time"
+)
+
+type config struct {
+	retries   int
+	backoff   time.Duration
+	exclusive bool
+
+	streamRetries int
+	streamDelay   time.Duration
+	noStreamOn    bool
+	prepareBufs   bool
+
+	bufType uint32
+
+	bytesPerLine int
+	sizeImage    int
+	field        uint32
+
+	hardwareJPEG bool
+
+	warmup int
+
+	minWidth, minHeight int
+
+	formats []Format
+
+	backend Backend
+	logger  Logger
+}
+
+// Option configures how Open sets up a device.
+type Option func(*config)
+
+func newConfig(opts []Option) config {
+
+	c := config{
+		streamRetries: 3,
+		streamDelay:   10 * time.Millisecond,
+		bufType:       V4L2_BUF_TYPE_VIDEO_CAPTURE,
+		backend:       systemBackend{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// WithOpenRetry retries opening a busy device up to n more times, waiting
+// backoff between attempts.
+func WithOpenRetry(n int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithExclusiveLock takes an advisory lock on a file named after the device,
+// in $XDG_RUNTIME_DIR or else the temporary directory, for as long as it is
+// open. Opening a device another process holds the lock for fails with
+// ErrDeviceBusy. Only processes of the same user, or sharing the
+// directory, that use the lock are kept out.
+func WithExclusiveLock() Option {
+	return func(c *config) {
+		c.exclusive = true
+	}
+}
+
+// WithStreamOnRetry retries starting the stream up to n more times, waiting
+// delay between attempts, when the driver fails it with EIO or EBUSY right
+// after buffers are requested, as some UVC cameras do. The default is 3
+// retries 10ms apart.
+func WithStreamOnRetry(n int, delay time.Duration) Option {
+	return func(c *config) {
+		c.streamRetries = n
+		c.streamDelay = delay
+	}
+}
+
+// WithBufferType captures buffers of type t instead of video, such as
+// V4L2_BUF_TYPE_SDR_CAPTURE, V4L2_BUF_TYPE_VBI_CAPTURE or
+// V4L2_BUF_TYPE_META_CAPTURE. Their frames are not images and can only be
+// read with GetRawFrame or, for metadata, GetMetadata; the width and
+// height given to Open are ignored. Open keeps the data format the device
+// has, while OpenFormat selects one on SDR and metadata devices.
+func WithBufferType(t uint32) Option {
+	return func(c *config) {
+		c.bufType = t
+	}
+}
+
+// WithBytesPerLine asks the driver for lines of bytesPerLine bytes and,
+// when sizeImage is not zero, for buffers of at least sizeImage bytes, to
+// match the alignment of a downstream consumer. Open fails if the driver
+// does not grant them. Zero leaves the choice to the driver.
+func WithBytesPerLine(bytesPerLine, sizeImage int) Option {
+	return func(c *config) {
+		c.bytesPerLine = bytesPerLine
+		c.sizeImage = sizeImage
+	}
+}
+
+// WithField requests the field order field, one of the V4L2_FIELD
+// constants, from the driver. Drivers are free to pick another; Field
+// reports the one in effect. The default leaves the choice to the driver.
+func WithField(field uint32) Option {
+	return func(c *config) {
+		c.field = field
+	}
+}
+
+// WithWarmup discards the first n frames after streaming starts, while
+// the camera's auto-exposure settles, so that the first frame returned is
+// not dark. See Device.WarmUp.
+func WithWarmup(n int) Option {
+	return func(c *config) {
+		c.warmup = n
+	}
+}
+
+// WithMinimumSize lets Open fall back to another frame size when the
+// device does not offer the one requested: of the sizes it enumerates that
+// are at least width x height it takes the largest that fits within the
+// request, or else the smallest that exceeds it. CurrentFormat reports the
+// size chosen. Open fails if the driver settles on anything smaller.
+func WithMinimumSize(width, height int) Option {
+	return func(c *config) {
+		c.minWidth = width
+		c.minHeight = height
+	}
+}
+
+// WithFormats has Open capture in the first of formats, in order of
+// preference, that the device enumerates, instead of the format it was
+// given. CurrentFormat reports the one chosen. Each must be a format
+// OpenFormat accepts.
+func WithFormats(formats ...Format) Option {
+	return func(c *config) {
+		c.formats = formats
+	}
+}
+
+// WithoutStreamOn skips the STREAMON ioctl after buffers are requested,
+// and STREAMOFF when streaming stops, for the odd driver that streams
+// implicitly once buffers are queued and fails STREAMON, which otherwise
+// makes Open fail. Those are mostly out-of-tree vendor drivers for the
+// camera interfaces of embedded SoCs, and older capture card drivers that
+// predate videobuf2; where Open fails on STREAMON with EINVAL or ENOTTY on
+// such hardware, this is worth a try. Mainline drivers, UVC cameras
+// included, do not need it.
+func WithoutStreamOn() Option {
+	return func(c *config) {
+		c.noStreamOn = true
+	}
+}
+
+// WithLogger routes the device's diagnostics to l from the start, so that
+// those of Open itself are logged too. See SetLogger.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithPrepareBuffers hands the device's reusable capture buffer to the
+// driver with PREPARE_BUF whenever streaming starts, so that the cache and
+// IOMMU setup of the memory is done once at setup time rather than at
+// every QBUF. It pays off on SoCs with non-coherent caches for captures
+// that reuse that buffer, as GetFrameInto, GetFrameYCbCrInto and streams
+// do; others queue their own memory as usual. Drivers without PREPARE_BUF
+// are queued to as usual too.
+func WithPrepareBuffers() Option {
+	return func(c *config) {
+		c.prepareBufs = true
+	}
+}
+
+// WithBackend issues the device's system calls through b instead of on the
+// real device node, so that capture can be exercised against a fake.
+func WithBackend(b Backend) Option {
+	return func(c *config) {
+		c.backend = b
+	}
+}
@@ -0,0 +1,276 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+const (
+	V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE uint32 = 10
+	V4L2_MEMORY_DMABUF                       = 4
+
+	V4L2_BUF_CAP_SUPPORTS_DMABUF uint32 = 0x00000004
+
+	// outputBuffers is how many buffers an output device cycles through.
+	outputBuffers = 4
+)
+
+// VIDEO_MAX_PLANES bounds the planes of a multi-planar format.
+const VIDEO_MAX_PLANES = 8
+
+type v4l2_plane_pix_format struct {
+	Sizeimage, Bytesperline uint32
+	Reserved                [6]uint16
+}
+
+type v4l2_pix_format_mplane struct {
+	Width, Height, Pixelformat, Field, Colorspace uint32
+	PlaneFmt                                      [VIDEO_MAX_PLANES]v4l2_plane_pix_format
+	NumPlanes, Flags                              uint8
+	YCBCREnc, Quantization, XferFunc              uint8
+	Reserved                                      [7]uint8
+}
+
+// v4l2_format with the multi-planar member of its union.
+type v4l2_format_mplane struct {
+	Type uint32
+	_    uint32
+
+	PixMp v4l2_pix_format_mplane
+	_     [8]uint8
+}
+
+// v4l2_plane with its memory union read as a 64-bit value; a dmabuf fd is
+// in the low half.
+type v4l2_plane struct {
+	Bytesused, Length uint32
+	M                 uint64
+	DataOffset        uint32
+	Reserved          [11]uint32
+}
+
+// OutputDevice feeds frames held in DMABUF buffers, such as ones a GPU
+// rendered into, to a multi-planar output device without copying them.
+type OutputDevice struct {
+	handle
+	pix v4l2_pix_format_mplane
+
+	free      []uint32
+	queued    int
+	streaming bool
+}
+
+// OpenOutput opens a multi-planar output device for frames of format
+// imported as DMABUF. The driver may adjust the size; Planes and PlaneSize
+// report what it settled on.
+func OpenOutput(device string, format Format, width, height int, opts ...Option) (*OutputDevice, error) {
+
+	config := newConfig(opts)
+	sys := config.backend
+
+	fd, err := sys.Open(device, os.O_RDWR|syscall.O_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open device: %w", busyError(err))
+	}
+
+	dev := &OutputDevice{handle: handle{device: device, fd: fd, sys: sys, logger: config.logger}}
+
+	fail := func(err error) (*OutputDevice, error) {
+		sys.Close(fd)
+		return nil, err
+	}
+
+	caps, err := dev.queryCap()
+	if err != nil {
+		return fail(fmt.Errorf("Failed to query capabilities: %v", err.Error()))
+	}
+
+	dev.caps = caps
+
+	if !dev.hasCap(V4L2_CAP_VIDEO_OUTPUT_MPLANE) {
+		return fail(errors.New("Not a multi-planar output device"))
+	}
+
+	f := v4l2_format_mplane{
+		Type: V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE,
+		PixMp: v4l2_pix_format_mplane{
+			Width:       uint32(width),
+			Height:      uint32(height),
+			Pixelformat: uint32(format),
+		},
+	}
+
+	b := toBytes(f)
+
+	if err := dev.ioctl(VIDIOC_S_FMT, toUintptr(b)); err != nil {
+		return fail(fmt.Errorf("Failed to set format: %w", busyError(err)))
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return fail(err)
+	}
+
+	dev.pix = f.PixMp
+
+	r := v4l2_requestbuffers{
+		Count:  outputBuffers,
+		Type:   V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE,
+		Memory: V4L2_MEMORY_DMABUF,
+	}
+
+	rb := toBytes(r)
+
+	if err := dev.ioctl(VIDIOC_REQBUFS, toUintptr(rb)); err != nil {
+		return fail(fmt.Errorf("Failed to request DMABUF buffers: %w", busyError(err)))
+	}
+
+	if err := fromBytes(rb, &r); err != nil {
+		return fail(err)
+	}
+
+	// Drivers older than the capability flags accept the request anyway,
+	// as the successful REQBUFS shows.
+	if r.Capabilities != 0 && r.Capabilities&V4L2_BUF_CAP_SUPPORTS_DMABUF == 0 {
+		return fail(errors.New("Device does not import DMABUF"))
+	}
+
+	if r.Count == 0 {
+		return fail(errors.New("Driver granted no buffers"))
+	}
+
+	if r.Count < outputBuffers {
+		dev.logf("Driver granted %d of %d buffers", r.Count, outputBuffers)
+	}
+
+	for i := uint32(0); i < r.Count; i++ {
+		dev.free = append(dev.free, i)
+	}
+
+	dev.buffers = int(r.Count)
+
+	return dev, nil
+}
+
+// Planes is the number of planes each frame is made of.
+func (dev *OutputDevice) Planes() int {
+	return int(dev.pix.NumPlanes)
+}
+
+// PlaneSize returns the size and line stride the driver expects of plane
+// i.
+func (dev *OutputDevice) PlaneSize(i int) (int, int) {
+	p := dev.pix.PlaneFmt[i]
+	return int(p.Sizeimage), int(p.Bytesperline)
+}
+
+// QueueDMABUF hands a frame to the device, one DMABUF fd per plane with
+// the number of bytes of each that hold data. The driver takes its own
+// reference to the buffers, so the fds may be closed once QueueDMABUF
+// returns, but the memory must not be rendered into again until the device
+// has shown the frame. The first frame starts streaming. When every buffer
+// is in use it waits for the device to finish with one, bounded by
+// SetIOTimeout.
+func (dev *OutputDevice) QueueDMABUF(fds []int, bytesused []int) error {
+
+	if len(fds) != dev.Planes() || len(bytesused) != len(fds) {
+		return fmt.Errorf("Frame has %d planes, the format has %d", len(fds), dev.Planes())
+	}
+
+	if len(dev.free) == 0 {
+		if err := dev.reclaim(); err != nil {
+			return err
+		}
+	}
+
+	index := dev.free[len(dev.free)-1]
+
+	planes := make([]v4l2_plane, len(fds))
+	for i, fd := range fds {
+		size, _ := dev.PlaneSize(i)
+		planes[i] = v4l2_plane{
+			Bytesused: uint32(bytesused[i]),
+			Length:    uint32(size),
+			M:         uint64(uint32(fd)),
+		}
+	}
+
+	pb := toBytes(planes)
+
+	qbuf := v4l2_buffer{
+		Index:   index,
+		Type:    V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE,
+		Memory:  V4L2_MEMORY_DMABUF,
+		Userptr: uint64(toUintptr(pb)),
+		Length:  uint32(len(planes)),
+	}
+
+	err := dev.ioctl(VIDIOC_QBUF, toUintptr(toBytes(qbuf)))
+	runtime.KeepAlive(pb)
+
+	if err != nil {
+		return fmt.Errorf("Failed to qbuf: %w", err)
+	}
+
+	dev.free = dev.free[:len(dev.free)-1]
+	dev.queued++
+
+	if !dev.streaming {
+		if err := dev.ioctl(VIDIOC_STREAMON, toUintptr(toBytes(V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE))); err != nil {
+			return fmt.Errorf("Failed to start streaming: %w", err)
+		}
+		dev.streaming = true
+	}
+
+	return nil
+}
+
+// reclaim waits for the device to finish with a queued buffer and makes it
+// free again.
+func (dev *OutputDevice) reclaim() error {
+
+	if dev.queued == 0 {
+		return errors.New("No buffers to reclaim")
+	}
+
+	planes := make([]v4l2_plane, dev.Planes())
+	pb := toBytes(planes)
+
+	qbuf := v4l2_buffer{
+		Type:    V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE,
+		Memory:  V4L2_MEMORY_DMABUF,
+		Userptr: uint64(toUintptr(pb)),
+		Length:  uint32(len(planes)),
+	}
+
+	b := toBytes(qbuf)
+
+	err := dev.ioctl(VIDIOC_DQBUF, toUintptr(b))
+	runtime.KeepAlive(pb)
+
+	if err != nil {
+		return fmt.Errorf("Failed to dqbuf: %w", err)
+	}
+
+	if err := fromBytes(b, &qbuf); err != nil {
+		return fmt.Errorf("Failed to read dqbuf: %v", err.Error())
+	}
+
+	dev.free = append(dev.free, qbuf.Index)
+	dev.queued--
+
+	return nil
+}
+
+// Close stops streaming, which returns every queued buffer, and closes the
+// device.
+func (dev *OutputDevice) Close() {
+
+	if dev.streaming {
+		dev.ioctl(VIDIOC_STREAMOFF, toUintptr(toBytes(V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE)))
+	}
+
+	dev.sys.Close(dev.fd)
+}
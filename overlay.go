@@ -0,0 +1,170 @@
+package v4l
+
+import (
+	"image"
+	"image/color"
+	"unicode"
+)
+
+// Corner is where in the frame an Overlay is drawn.
+type Corner int
+
+const (
+	TopLeft Corner = iota
+	TopRight
+	BottomLeft
+	BottomRight
+)
+
+// Overlay is text burned into every converted frame, such as a timestamp
+// for evidentiary recording.
+type Overlay struct {
+	// Format is a time layout, as time.Time's Format takes, that is
+	// rendered with the capture time; text that is not part of a layout,
+	// such as a camera name, is drawn as is.
+	Format   string
+	Position Corner
+	Color    color.RGBA
+
+	// Background, when not fully transparent, fills a box behind the text
+	// to keep it legible over bright scenes.
+	Background color.RGBA
+
+	// Scale is the size in pixels of a dot of the 5x7 font; values below
+	// one are taken as one.
+	Scale int
+}
+
+// SetOverlay makes GetFrame and the other converting captures draw o into
+// their frames after conversion and any transform. The built-in font
+// covers digits, letters, drawn in upper case, and common punctuation;
+// other characters are left blank. A nil o, the default, draws nothing.
+func (dev *Device) SetOverlay(o *Overlay) {
+	dev.overlay = o
+}
+
+// drawOverlay draws the device's overlay, if any, into im, rendering the
+// time the frame described by info was captured.
+func (dev *Device) drawOverlay(im *image.RGBA, info FrameInfo) {
+
+	if o := dev.overlay; o != nil {
+		o.draw(im, info.Time().Format(o.Format))
+	}
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+func (o *Overlay) draw(im *image.RGBA, text string) {
+
+	s := o.Scale
+	if s < 1 {
+		s = 1
+	}
+
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return
+	}
+
+	margin := 4 * s
+	w := (len(runes)*(glyphWidth+1) - 1) * s
+	h := glyphHeight * s
+
+	b := im.Rect
+	x, y := b.Min.X+margin, b.Min.Y+margin
+
+	if o.Position == TopRight || o.Position == BottomRight {
+		x = b.Max.X - margin - w
+	}
+	if o.Position == BottomLeft || o.Position == BottomRight {
+		y = b.Max.Y - margin - h
+	}
+
+	if o.Background.A != 0 {
+		fillRect(im, image.Rect(x-s, y-s, x+w+s, y+h+s), o.Background)
+	}
+
+	for i, r := range runes {
+
+		g := glyph(r)
+		gx := x + i*(glyphWidth+1)*s
+
+		for col, bits := range g {
+			for row := 0; row < glyphHeight; row++ {
+				if bits&(1<<uint(row)) != 0 {
+					fillRect(im, image.Rect(gx+col*s, y+row*s, gx+(col+1)*s, y+(row+1)*s), o.Color)
+				}
+			}
+		}
+	}
+}
+
+// fillRect paints r, clipped to im, in c.
+func fillRect(im *image.RGBA, r image.Rectangle, c color.RGBA) {
+
+	r = r.Intersect(im.Rect)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		p := im.Pix[im.PixOffset(r.Min.X, y):]
+		for x := 0; x < r.Dx(); x++ {
+			p[x*4+0], p[x*4+1], p[x*4+2], p[x*4+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+func glyph(r rune) [glyphWidth]uint8 {
+	return font5x7[unicode.ToUpper(r)]
+}
+
+// font5x7 holds a glyph's columns from left to right, with the top row in
+// the lowest bit.
+var font5x7 = map[rune][glyphWidth]uint8{
+	'0': {0x3E, 0x51, 0x49, 0x45, 0x3E},
+	'1': {0x00, 0x42, 0x7F, 0x40, 0x00},
+	'2': {0x42, 0x61, 0x51, 0x49, 0x46},
+	'3': {0x21, 0x41, 0x45, 0x4B, 0x31},
+	'4': {0x18, 0x14, 0x12, 0x7F, 0x10},
+	'5': {0x27, 0x45, 0x45, 0x45, 0x39},
+	'6': {0x3C, 0x4A, 0x49, 0x49, 0x30},
+	'7': {0x01, 0x71, 0x09, 0x05, 0x03},
+	'8': {0x36, 0x49, 0x49, 0x49, 0x36},
+	'9': {0x06, 0x49, 0x49, 0x29, 0x1E},
+	'A': {0x7E, 0x11, 0x11, 0x11, 0x7E},
+	'B': {0x7F, 0x49, 0x49, 0x49, 0x36},
+	'C': {0x3E, 0x41, 0x41, 0x41, 0x22},
+	'D': {0x7F, 0x41, 0x41, 0x22, 0x1C},
+	'E': {0x7F, 0x49, 0x49, 0x49, 0x41},
+	'F': {0x7F, 0x09, 0x09, 0x09, 0x01},
+	'G': {0x3E, 0x41, 0x49, 0x49, 0x7A},
+	'H': {0x7F, 0x08, 0x08, 0x08, 0x7F},
+	'I': {0x00, 0x41, 0x7F, 0x41, 0x00},
+	'J': {0x20, 0x40, 0x41, 0x3F, 0x01},
+	'K': {0x7F, 0x08, 0x14, 0x22, 0x41},
+	'L': {0x7F, 0x40, 0x40, 0x40, 0x40},
+	'M': {0x7F, 0x02, 0x0C, 0x02, 0x7F},
+	'N': {0x7F, 0x04, 0x08, 0x10, 0x7F},
+	'O': {0x3E, 0x41, 0x41, 0x41, 0x3E},
+	'P': {0x7F, 0x09, 0x09, 0x09, 0x06},
+	'Q': {0x3E, 0x41, 0x51, 0x21, 0x5E},
+	'R': {0x7F, 0x09, 0x19, 0x29, 0x46},
+	'S': {0x46, 0x49, 0x49, 0x49, 0x31},
+	'T': {0x01, 0x01, 0x7F, 0x01, 0x01},
+	'U': {0x3F, 0x40, 0x40, 0x40, 0x3F},
+	'V': {0x1F, 0x20, 0x40, 0x20, 0x1F},
+	'W': {0x3F, 0x40, 0x38, 0x40, 0x3F},
+	'X': {0x63, 0x14, 0x08, 0x14, 0x63},
+	'Y': {0x07, 0x08, 0x70, 0x08, 0x07},
+	'Z': {0x61, 0x51, 0x49, 0x45, 0x43},
+	':': {0x00, 0x36, 0x36, 0x00, 0x00},
+	'-': {0x08, 0x08, 0x08, 0x08, 0x08},
+	'+': {0x08, 0x08, 0x3E, 0x08, 0x08},
+	'.': {0x00, 0x60, 0x60, 0x00, 0x00},
+	',': {0x00, 0x50, 0x30, 0x00, 0x00},
+	'/': {0x20, 0x10, 0x08, 0x04, 0x02},
+	'_': {0x40, 0x40, 0x40, 0x40, 0x40},
+	'(': {0x00, 0x1C, 0x22, 0x41, 0x00},
+	')': {0x00, 0x41, 0x22, 0x1C, 0x00},
+}
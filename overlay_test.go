@@ -0,0 +1,38 @@
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestOverlayUsesCaptureTime(t *testing.T) {
+
+	o := &Overlay{Format: "15:04:05", Color: color.RGBA{255, 255, 255, 255}}
+	dev := &Device{overlay: o}
+
+	// A frame captured a little over an hour before now.
+	info := FrameInfo{
+		Flags:     V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC,
+		Timestamp: monotonicNow() - 61*time.Minute,
+	}
+
+	got := image.NewRGBA(image.Rect(0, 0, 80, 16))
+	dev.drawOverlay(got, info)
+
+	want := image.NewRGBA(got.Rect)
+	o.draw(want, info.Time().Format(o.Format))
+
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("overlay does not show the capture time")
+	}
+
+	now := image.NewRGBA(got.Rect)
+	o.draw(now, time.Now().Format(o.Format))
+
+	if bytes.Equal(got.Pix, now.Pix) {
+		t.Error("overlay shows the current time")
+	}
+}
@@ -0,0 +1,64 @@
+package v4l
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	V4L2_CAP_TIMEPERFRAME uint32 = 0x1000
+
+	VIDIOC_G_PARM uintptr = 0xC0CC5615
+)
+
+type v4l2_fract struct {
+	Numerator, Denominator uint32
+}
+
+type v4l2_captureparm struct {
+	Capability, Capturemode   uint32
+	Timeperframe              v4l2_fract
+	Extendedmode, Readbuffers uint32
+	Reserved                  [4]uint32
+}
+
+// v4l2_streamparm with its union cut down to the capture member.
+type v4l2_streamparm struct {
+	Type    uint32
+	Capture v4l2_captureparm
+	_       [160]uint8
+}
+
+// FrameInterval returns the time between frames the driver is set to
+// deliver at.
+func (dev *Device) FrameInterval() (time.Duration, error) {
+
+	f, err := dev.timePerFrame()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(f.Numerator) * time.Second / time.Duration(f.Denominator), nil
+}
+
+func (dev *Device) timePerFrame() (v4l2_fract, error) {
+
+	p := v4l2_streamparm{Type: V4L2_BUF_TYPE_VIDEO_CAPTURE}
+
+	b := toBytes(p)
+
+	if err := dev.ioctl(VIDIOC_G_PARM, toUintptr(b)); err != nil {
+		return v4l2_fract{}, fmt.Errorf("Failed to get stream parameters: %v", err.Error())
+	}
+
+	if err := fromBytes(b, &p); err != nil {
+		return v4l2_fract{}, err
+	}
+
+	f := p.Capture.Timeperframe
+	if p.Capture.Capability&V4L2_CAP_TIMEPERFRAME == 0 || f.Numerator == 0 || f.Denominator == 0 {
+		return v4l2_fract{}, fmt.Errorf("Driver does not report a frame interval")
+	}
+
+	return f, nil
+}
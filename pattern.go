@@ -0,0 +1,390 @@
+package v4l
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Pattern selects what OpenTestPattern draws.
+type Pattern int
+
+const (
+	// PatternBars draws SMPTE colour bars.
+	PatternBars Pattern = iota
+	// PatternCheckerboard draws black and white squares.
+	PatternCheckerboard
+	// PatternGradient draws a grey ramp that scrolls a little every frame.
+	PatternGradient
+	// PatternNoise draws random grey pixels.
+	PatternNoise
+)
+
+func (p Pattern) String() string {
+
+	switch p {
+	case PatternBars:
+		return "SMPTE bars"
+	case PatternCheckerboard:
+		return "checkerboard"
+	case PatternGradient:
+		return "gradient"
+	case PatternNoise:
+		return "noise"
+	}
+
+	return "unknown pattern"
+}
+
+// checkerSize is the side of a PatternCheckerboard square in pixels.
+const checkerSize = 32
+
+// OpenTestPattern opens a virtual capture device that draws pattern at
+// width x height, delivering fps frames per second, or 30 when fps is not
+// positive. It captures in V4L2_PIX_FMT_RGBA32 and works with GetFrame,
+// Stream and the other capture methods like a camera does, so code can be
+// developed and demonstrated without one. It has no controls.
+func OpenTestPattern(pattern Pattern, width, height, fps int, opts ...Option) (*Device, error) {
+
+	if fps <= 0 {
+		fps = 30
+	}
+
+	b := &patternBackend{pattern: pattern, interval: time.Second / time.Duration(fps)}
+
+	opts = append(append([]Option(nil), opts...), WithBackend(b))
+
+	return OpenFormat("testpattern", V4L2_PIX_FMT_RGBA32, width, height, opts...)
+}
+
+// patternBackend answers the requests a Device makes as a USERPTR capture
+// driver would, drawing each frame into the buffer it is handed instead of
+// issuing any system calls. As with a real driver, the format belongs to
+// the device and the stream to the file handle that requested buffers, so
+// a second handle, such as a Clone, leaves the stream alone.
+type patternBackend struct {
+	pattern  Pattern
+	interval time.Duration
+
+	mu      sync.Mutex
+	pix     v4l2_pix_format
+	streams map[int]*patternStream
+	nextFd  int
+	rand    *rand.Rand
+}
+
+// patternStream is the stream state of one open file handle.
+type patternStream struct {
+	streaming bool
+	queued    []v4l2_buffer
+	start     time.Time
+	sequence  uint32
+}
+
+func (p *patternBackend) Open(path string, flags int) (int, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.streams == nil {
+		p.streams = make(map[int]*patternStream)
+		p.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	fd := p.nextFd
+	p.nextFd++
+	p.streams[fd] = &patternStream{}
+
+	return fd, nil
+}
+
+func (p *patternBackend) Close(fd int) error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.streams, fd)
+
+	return nil
+}
+
+// streaming reports whether any handle is streaming, which keeps the
+// format from being changed.
+func (p *patternBackend) streaming() bool {
+
+	for _, st := range p.streams {
+		if st.streaming {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *patternBackend) Ioctl(fd int, req, arg uintptr) error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.streams[fd]
+	if st == nil {
+		return patternError(syscall.EBADF)
+	}
+
+	switch req {
+
+	case VIDIOC_QUERYCAP:
+		c := v4l2_capability{
+			Capabilities: V4L2_CAP_VIDEO_CAPTURE | V4L2_CAP_STREAMING | V4L2_CAP_DEVICE_CAPS,
+			DeviceCaps:   V4L2_CAP_VIDEO_CAPTURE | V4L2_CAP_STREAMING,
+		}
+		copy(c.Driver[:], "testpattern")
+		copy(c.Card[:], p.pattern.String())
+		copy(argBytes(arg, binary.Size(c)), toBytes(c))
+
+	case VIDIOC_ENUM_FMT:
+		var d v4l2_fmtdesc
+		b := readArg(arg, &d)
+		if d.Index != 0 || d.Type != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+			return patternError(syscall.EINVAL)
+		}
+		d.Pixelformat = uint32(V4L2_PIX_FMT_RGBA32)
+		copy(d.Description[:], "32-bit RGBA 8-8-8-8")
+		copy(b, toBytes(d))
+
+	case VIDIOC_S_FMT:
+		var f v4l2_format
+		b := readArg(arg, &f)
+		if f.Type != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+			return patternError(syscall.EINVAL)
+		}
+		if p.streaming() {
+			return patternError(syscall.EBUSY)
+		}
+		w, h := int(f.Pix.Width)&^1, int(f.Pix.Height)
+		if w < 2 {
+			w = 2
+		}
+		if h < 1 {
+			h = 1
+		}
+		p.pix = v4l2_pix_format{
+			Width:        uint32(w),
+			Height:       uint32(h),
+			Pixelformat:  uint32(V4L2_PIX_FMT_RGBA32),
+			Field:        V4L2_FIELD_NONE,
+			Bytesperline: uint32(w * 4),
+			Sizeimage:    uint32(w * h * 4),
+		}
+		f.Pix = p.pix
+		copy(b, toBytes(f))
+
+	case VIDIOC_G_FMT:
+		var f v4l2_format
+		b := readArg(arg, &f)
+		f.Pix = p.pix
+		copy(b, toBytes(f))
+
+	case VIDIOC_REQBUFS:
+		var r v4l2_requestbuffers
+		b := readArg(arg, &r)
+		if r.Memory != V4L2_MEMORY_USERPTR || st.streaming {
+			return patternError(syscall.EINVAL)
+		}
+		if r.Count > 1 {
+			r.Count = 1
+		}
+		st.queued = nil
+		copy(b, toBytes(r))
+
+	case VIDIOC_QBUF:
+		var q v4l2_buffer
+		readArg(arg, &q)
+		if q.Memory != V4L2_MEMORY_USERPTR || q.Length < p.pix.Sizeimage || len(st.queued) > 0 {
+			return patternError(syscall.EINVAL)
+		}
+		st.queued = append(st.queued, q)
+
+	case VIDIOC_DQBUF:
+		var q v4l2_buffer
+		b := readArg(arg, &q)
+		if !st.streaming || len(st.queued) == 0 {
+			return patternError(syscall.EINVAL)
+		}
+
+		// The lock only guards the state; frames are waited for outside it
+		// so that STREAMOFF can still get through.
+		due := p.due(st)
+		p.mu.Unlock()
+		time.Sleep(time.Until(due))
+		p.mu.Lock()
+
+		if len(st.queued) == 0 {
+			return patternError(syscall.EINVAL)
+		}
+		q = st.queued[0]
+		st.queued = st.queued[1:]
+
+		frame := argBytes(uintptr(q.Userptr), int(p.pix.Sizeimage))
+		p.draw(frame, st.sequence)
+
+		// Timestamps are taken on CLOCK_MONOTONIC, as drivers do now.
+		stamp := monotonicNow()
+		q.Flags = V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC
+		q.Bytesused = p.pix.Sizeimage
+		q.Field = V4L2_FIELD_NONE
+		q.Sequence = st.sequence
+		q.TvSec = uint64(stamp / time.Second)
+		q.TvUsec = uint64(stamp % time.Second / time.Microsecond)
+		st.sequence++
+		copy(b, toBytes(q))
+
+	case VIDIOC_STREAMON:
+		if !st.streaming {
+			st.streaming = true
+			st.start = time.Now()
+			st.sequence = 0
+		}
+
+	case VIDIOC_STREAMOFF:
+		st.streaming = false
+		st.queued = nil
+
+	default:
+		return patternError(syscall.EINVAL)
+	}
+
+	return nil
+}
+
+func (p *patternBackend) Poll(fd int, events int16, timeout time.Duration) (int16, error) {
+
+	p.mu.Lock()
+	st := p.streams[fd]
+	ready := st != nil && st.streaming && len(st.queued) > 0
+	var due time.Time
+	if st != nil {
+		due = p.due(st)
+	}
+	p.mu.Unlock()
+
+	if events&POLLIN == 0 {
+		time.Sleep(timeout)
+		return 0, ErrTimeout
+	}
+
+	if !ready {
+		return POLLERR, nil
+	}
+
+	wait := time.Until(due)
+	if timeout < wait {
+		time.Sleep(timeout)
+		return 0, ErrTimeout
+	}
+
+	time.Sleep(wait)
+
+	return POLLIN, nil
+}
+
+// due is when the next frame of st is ready.
+func (p *patternBackend) due(st *patternStream) time.Time {
+	return st.start.Add(time.Duration(st.sequence) * p.interval)
+}
+
+// draw fills frame, laid out as p.pix, with the pattern for frame number
+// sequence.
+func (p *patternBackend) draw(frame []byte, sequence uint32) {
+
+	w, h := int(p.pix.Width), int(p.pix.Height)
+
+	for y := 0; y < h; y++ {
+
+		row := frame[y*w*4 : (y+1)*w*4]
+
+		for x := 0; x < w; x++ {
+
+			var r, g, b uint8
+
+			switch p.pattern {
+			case PatternBars:
+				r, g, b = smpteBar(x, y, w, h)
+			case PatternCheckerboard:
+				if (x/checkerSize+y/checkerSize)%2 == 0 {
+					r, g, b = 255, 255, 255
+				}
+			case PatternGradient:
+				v := uint8((x + int(sequence)*4) * 256 / w)
+				r, g, b = v, v, v
+			case PatternNoise:
+				v := uint8(p.rand.Intn(256))
+				r, g, b = v, v, v
+			}
+
+			row[x*4+0] = r
+			row[x*4+1] = g
+			row[x*4+2] = b
+			row[x*4+3] = 255
+		}
+	}
+}
+
+// smpteBars are the 75% bars across the top two thirds, followed by the
+// reversed blue bars of the narrow strip beneath them.
+var smpteBars = [2][7][3]uint8{
+	{{191, 191, 191}, {191, 191, 0}, {0, 191, 191}, {0, 191, 0}, {191, 0, 191}, {191, 0, 0}, {0, 0, 191}},
+	{{0, 0, 191}, {19, 19, 19}, {191, 0, 191}, {19, 19, 19}, {0, 191, 191}, {19, 19, 19}, {191, 191, 191}},
+}
+
+// smpteBar is the colour of pixel x, y of SMPTE bars w x h. The bottom
+// quarter holds -I, white and +Q patches each 5/4 of a bar wide, then
+// black.
+func smpteBar(x, y, w, h int) (uint8, uint8, uint8) {
+
+	switch {
+	case y < h*2/3:
+		c := smpteBars[0][x*7/w]
+		return c[0], c[1], c[2]
+	case y < h*3/4:
+		c := smpteBars[1][x*7/w]
+		return c[0], c[1], c[2]
+	}
+
+	switch x * 28 / w / 5 {
+	case 0:
+		return 0, 33, 76
+	case 1:
+		return 255, 255, 255
+	case 2:
+		return 50, 0, 106
+	}
+
+	return 19, 19, 19
+}
+
+// argBytes views the n bytes at arg, where the caller laid out the struct
+// of a request, as a driver reading it would. arg is reinterpreted in
+// place rather than converted, as it is the address of memory the caller
+// keeps alive for the duration of the request.
+func argBytes(arg uintptr, n int) []byte {
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&arg)), n)
+}
+
+// readArg decodes the struct at arg into v and returns its bytes, for the
+// answer to be written back into.
+func readArg(arg uintptr, v interface{}) []byte {
+
+	b := argBytes(arg, binary.Size(v))
+	fromBytes(b, v)
+
+	return b
+}
+
+func patternError(e syscall.Errno) error {
+	return os.NewSyscallError("ioctl", e)
+}
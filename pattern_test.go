@@ -0,0 +1,156 @@
+package v4l
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPatternCloneKeepsStream(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := dev.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatalf("capture after Clone: %v", err)
+	}
+}
+
+func TestTimeoutTakesBufferBack(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	// The first frame is due at once; the second a second later.
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	dev.SetIOTimeout(10 * time.Millisecond)
+
+	if _, err := dev.GetFrame(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("GetFrame = %v, want ErrTimeout", err)
+	}
+
+	p := dev.sys.(*patternBackend)
+	p.mu.Lock()
+	queued := len(p.streams[dev.fd].queued)
+	p.mu.Unlock()
+
+	if queued != 0 {
+		t.Fatalf("%d buffers still queued after the timeout", queued)
+	}
+
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatalf("capture after the timeout: %v", err)
+	}
+}
+
+func TestOpenReportsSubstitutedFormat(t *testing.T) {
+
+	b := &patternBackend{pattern: PatternBars, interval: time.Second / 30}
+
+	dev, err := OpenFormat("pattern", V4L2_PIX_FMT_YUYV, 64, 48, WithBackend(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	a := dev.FormatAdjustment()
+	if !a.PixelFormatChanged() || a.Actual.PixelFormat != V4L2_PIX_FMT_RGBA32 {
+		t.Fatalf("adjustment %+v does not report RGBA32 for YUYV", a)
+	}
+
+	if dev.format != V4L2_PIX_FMT_RGBA32 {
+		t.Fatalf("device captures in %v, not the substituted RGBA32", dev.format)
+	}
+
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGrabAsWhileStreaming(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	s := dev.Stream()
+	<-s.C
+
+	if _, err := dev.GrabAs(V4L2_PIX_FMT_RGBA32, 32, 24); err == nil {
+		t.Error("GrabAs succeeded while a stream owned the queue")
+	}
+
+	if err := dev.Flush(); err == nil {
+		t.Error("Flush succeeded while a stream owned the queue")
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dev.queueBusy("change format"); err != nil {
+		t.Errorf("queue still busy after Stop: %v", err)
+	}
+}
+
+func TestLatestFrameAfterClose(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dev.LatestFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	dev.Close()
+
+	if _, err := dev.LatestFrame(); !errors.Is(err, ErrDeviceClosed) {
+		t.Fatalf("LatestFrame after Close = %v, want ErrDeviceClosed", err)
+	}
+}
+
+func TestCaptureAfterBackground(t *testing.T) {
+
+	dev, err := OpenTestPattern(PatternBars, 64, 48, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	if err := dev.EnableBackgroundCapture(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dev.CurrentFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	dev.DisableBackgroundCapture()
+
+	if _, err := dev.GetFrame(); err != nil {
+		t.Fatalf("capture after background capture: %v", err)
+	}
+}
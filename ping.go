@@ -0,0 +1,38 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// ErrDeviceGone is returned by Ping once the device has been unplugged or
+// its node closed. Only reopening it can help then.
+var ErrDeviceGone = errors.New("Device gone")
+
+// Ping issues a cheap request, QUERYCAP, to check whether the device still
+// responds, without capturing anything. It returns ErrDeviceGone for a
+// device that has gone away and otherwise the error the driver returned,
+// wrapped so that errors.Is finds the errno.
+func (dev *handle) Ping() error {
+
+	if dev.fd < 0 {
+		return ErrDeviceGone
+	}
+
+	if _, err := dev.queryCap(); err != nil {
+		if errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.EBADF) {
+			return ErrDeviceGone
+		}
+		return fmt.Errorf("Failed to query capabilities: %w", err)
+	}
+
+	return nil
+}
+
+// IsAlive reports whether the device is still there. Transient errors
+// count as alive; only a device that has gone away does not, which is
+// when a watchdog should reconnect.
+func (dev *handle) IsAlive() bool {
+	return dev.Ping() != ErrDeviceGone
+}
@@ -0,0 +1,61 @@
+package v4l
+
+import (
+	"fmt"
+)
+
+// Planes is a YUV frame as the driver delivered it, for encoders that take
+// the samples as they are rather than an image to convert back. The
+// slices share the captured buffer; nothing is copied or resampled.
+type Planes struct {
+	Format        Format
+	Width, Height int
+	Info          FrameInfo
+
+	// Y is the full resolution luma plane of a semi-planar format, lines
+	// YStride bytes apart, and CbCr the half resolution plane of chroma
+	// pairs after it, lines CStride bytes apart. NV12 stores Cb first in
+	// each pair and NV21 Cr first.
+	Y, CbCr          []byte
+	YStride, CStride int
+
+	// Packed holds the lines of a packed 4:2:2 format, Stride bytes apart,
+	// each pair of pixels in four bytes ordered as the format's name
+	// spells: Y0 Cb Y1 Cr for YUYV, Cb Y0 Cr Y1 for UYVY and so on.
+	Packed []byte
+	Stride int
+}
+
+// GetPlanes captures a frame of a YUV format and returns its planes
+// without any RGB conversion. Formats that are not YUV fail.
+func (dev *Device) GetPlanes() (Planes, error) {
+
+	_, packed := packed422Layouts[dev.format]
+	if !packed && !semiPlanar(dev.format) {
+		return Planes{}, fmt.Errorf("Unsupported format for planes: %v", dev.format)
+	}
+
+	frame, info, err := dev.readFrame()
+	if err != nil {
+		return Planes{}, err
+	}
+
+	p := Planes{Format: dev.format, Width: dev.width, Height: dev.height, Info: info}
+	stride := dev.stride()
+
+	if packed {
+		frame = padFrame(frame, stride*dev.height)
+		p.Packed = frame[:stride*dev.height]
+		p.Stride = stride
+		return p, nil
+	}
+
+	chroma := stride * ((dev.height + 1) / 2)
+	frame = padFrame(frame, stride*dev.height+chroma)
+
+	p.Y = frame[:stride*dev.height]
+	p.CbCr = frame[stride*dev.height : stride*dev.height+chroma]
+	p.YStride, p.CStride = stride, stride
+
+	return p, nil
+}
@@ -0,0 +1,104 @@
+package v4l
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var ErrTimeout = errors.New("Timed out")
+
+const (
+	POLLIN  int16 = 0x0001
+	POLLPRI       = 0x0002
+	POLLOUT       = 0x0004
+	POLLERR       = 0x0008
+)
+
+type pollFd struct {
+	Fd      int32
+	Events  int16
+	Revents int16
+}
+
+// SetIOTimeout bounds how long an ioctl that waits on the driver, such as
+// dequeuing a buffer, may block before failing with ErrTimeout. Zero, the
+// default, waits forever.
+func (dev *handle) SetIOTimeout(d time.Duration) {
+	dev.timeout = d
+}
+
+// ioctl issues req on the device, first polling for the readiness the
+// request waits on when a timeout is set. Requests that do not wait on the
+// driver go straight through.
+func (dev *handle) ioctl(req, arg uintptr) error {
+
+	if events := pollEvents(req); dev.timeout > 0 && events != 0 {
+		if _, err := dev.sys.Poll(dev.fd, events, dev.timeout); err != nil {
+			return err
+		}
+	}
+
+	err := dev.sys.Ioctl(dev.fd, req, arg)
+	if err != nil {
+		dev.logf("IOCTL[%d::%x]: %v", dev.fd, req, err)
+	}
+
+	return err
+}
+
+// pollEvents is the readiness a request waits on, or zero for requests
+// that never block: a filled capture or emptied output buffer for DQBUF,
+// and a pending event for DQEVENT.
+func pollEvents(req uintptr) int16 {
+
+	switch req {
+	case VIDIOC_DQBUF:
+		return POLLIN | POLLOUT
+	case VIDIOC_DQEVENT:
+		return POLLPRI
+	}
+
+	return 0
+}
+
+// poll waits up to timeout for events on fd and returns the events that
+// occurred. A zero timeout checks once without waiting.
+func poll(fd int, events int16, timeout time.Duration) (int16, error) {
+
+	p := pollFd{Fd: int32(fd), Events: events}
+	deadline := time.Now().Add(timeout)
+
+	for {
+
+		d := time.Until(deadline)
+		if d < 0 {
+			d = 0
+		}
+		ts := syscall.NsecToTimespec(int64(d))
+
+		n, _, e := syscall.Syscall6(syscall.SYS_PPOLL,
+			uintptr(unsafe.Pointer(&p)), 1, uintptr(unsafe.Pointer(&ts)), 0, 0, 0)
+
+		if e == syscall.EINTR {
+			continue
+		}
+		if e != 0 {
+			return 0, os.NewSyscallError("ppoll", e)
+		}
+		if n == 0 {
+			return 0, ErrTimeout
+		}
+
+		return p.Revents, nil
+	}
+}
+
+// eventPending reports, without waiting, whether the driver has an event
+// queued for DQEVENT.
+func (dev *handle) eventPending() bool {
+	revents, err := dev.sys.Poll(dev.fd, POLLPRI, 0)
+	return err == nil && revents&POLLPRI != 0
+}
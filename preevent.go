@@ -0,0 +1,171 @@
+package v4l
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BufferedFrame is a raw frame held by a PreEventBuffer, as GetRawFrame
+// returns it, with the time it was captured.
+type BufferedFrame struct {
+	Data []byte
+	Info FrameInfo
+	Time time.Time
+}
+
+// PreEventBuffer keeps the most recent frames of a device so that, like a
+// dashcam, the moments before an event can be saved once it is noticed.
+type PreEventBuffer struct {
+	dev      *Device
+	duration time.Duration
+	maxBytes int
+
+	mu     sync.Mutex
+	frames []BufferedFrame
+	bytes  int
+	err    error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// PreEventBuffer starts capturing on a background goroutine into a ring
+// holding the last d of frames, bounded by maxBytes of frame data when
+// maxBytes is positive, whichever is shorter. Frames are kept raw, so
+// compressed formats such as MJPEG hold many seconds of high resolution
+// video in the memory a few converted images would take, and the buffers
+// of frames that age out are reused for new ones. The buffer owns the
+// device's queue until Stop, so the device must not be captured from
+// otherwise in the meantime.
+func (dev *Device) PreEventBuffer(d time.Duration, maxBytes int) *PreEventBuffer {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := &PreEventBuffer{
+		dev:      dev,
+		duration: d,
+		maxBytes: maxBytes,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	dev.beginStream()
+	go p.run(ctx)
+
+	return p
+}
+
+func (p *PreEventBuffer) run(ctx context.Context) {
+
+	defer close(p.done)
+
+	dev := p.dev
+	defer dev.endStream()
+
+	for ctx.Err() == nil {
+
+		buf := dev.spareBuffer()
+
+		info, err := dev.captureContext(ctx, buf)
+		if err == context.Canceled {
+			dev.putBuffer(buf)
+			return
+		}
+		if err == errSourceChanged {
+			continue
+		}
+		if err == ErrFrameCorrupt {
+			dev.putBuffer(buf)
+			continue
+		}
+		if err != nil {
+			dev.putBuffer(buf)
+			p.mu.Lock()
+			p.err = err
+			p.mu.Unlock()
+			return
+		}
+
+		data := buf
+
+		// Compressed frames are copied out at the size the driver filled
+		// in, so the ring does not hold a whole raw sized buffer for each.
+		if dev.format.Compressed() && info.Bytesused != 0 && int(info.Bytesused) < len(buf) {
+			data = append([]byte(nil), buf[:info.Bytesused]...)
+			dev.putBuffer(buf)
+		}
+
+		p.push(BufferedFrame{Data: data, Info: info, Time: info.Time()})
+	}
+}
+
+// push appends f and drops the oldest frames that fall outside the ring's
+// duration or byte budget, always keeping the newest frame.
+func (p *PreEventBuffer) push(f BufferedFrame) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.frames = append(p.frames, f)
+	p.bytes += len(f.Data)
+
+	for len(p.frames) > 1 {
+
+		old := p.frames[0]
+		if f.Time.Sub(old.Time) <= p.duration && (p.maxBytes <= 0 || p.bytes <= p.maxBytes) {
+			break
+		}
+
+		p.frames[0] = BufferedFrame{}
+		p.frames = p.frames[1:]
+		p.bytes -= len(old.Data)
+
+		if len(old.Data) == p.dev.frameSize() {
+			p.dev.putBuffer(old.Data)
+		}
+	}
+}
+
+// Trigger hands over the buffered frames, oldest first, and starts
+// filling the ring afresh. Until the ring has been running for its full
+// duration the frames cover less than that; Buffered tells how much. The
+// returned frames belong to the caller.
+func (p *PreEventBuffer) Trigger() []BufferedFrame {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	frames := p.frames
+	p.frames = nil
+	p.bytes = 0
+
+	return frames
+}
+
+// Buffered returns the time span between the oldest and newest frame in
+// the ring.
+func (p *PreEventBuffer) Buffered() time.Duration {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.frames) < 2 {
+		return 0
+	}
+
+	return p.frames[len(p.frames)-1].Time.Sub(p.frames[0].Time)
+}
+
+// Stop ends capturing and returns the error that ended it early, if any.
+// Frames still buffered can be taken with Trigger afterwards.
+func (p *PreEventBuffer) Stop() error {
+
+	p.cancel()
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
@@ -0,0 +1,330 @@
+package v4l
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+const (
+	V4L2_FMT_FLAG_COMPRESSED uint32 = 0x0001
+	V4L2_FMT_FLAG_EMULATED          = 0x0002
+
+	V4L2_FRMSIZE_TYPE_DISCRETE   uint32 = 1
+	V4L2_FRMSIZE_TYPE_CONTINUOUS        = 2
+	V4L2_FRMSIZE_TYPE_STEPWISE          = 3
+
+	V4L2_FRMIVAL_TYPE_DISCRETE   uint32 = 1
+	V4L2_FRMIVAL_TYPE_CONTINUOUS        = 2
+	V4L2_FRMIVAL_TYPE_STEPWISE          = 3
+
+	VIDIOC_ENUM_FMT            uintptr = 0xC0405602
+	VIDIOC_ENUM_FRAMESIZES             = 0xC02C564A
+	VIDIOC_ENUM_FRAMEINTERVALS         = 0xC034564B
+)
+
+type v4l2_fmtdesc struct {
+	Index, Type, Flags uint32
+	Description        [32]uint8
+	Pixelformat        uint32
+	MbusCode           uint32
+	Reserved           [3]uint32
+}
+
+// v4l2_frmsizeenum with its union read as the six fields of the stepwise
+// member; a discrete size is in the first two.
+type v4l2_frmsizeenum struct {
+	Index, PixelFormat, Type uint32
+	Union                    [6]uint32
+	Reserved                 [2]uint32
+}
+
+// v4l2_frmivalenum with its union read as the three fractions of the
+// stepwise member; a discrete interval is the first.
+type v4l2_frmivalenum struct {
+	Index, PixelFormat, Width, Height, Type uint32
+	Union                                   [3]v4l2_fract
+	Reserved                                [2]uint32
+}
+
+// DeviceProfile is everything a device can capture: each format, the
+// frame sizes it comes in and the frame intervals of each size.
+type DeviceProfile struct {
+	Formats []FormatProfile
+}
+
+type FormatProfile struct {
+	Format      Format
+	Description string
+	Flags       uint32
+	Sizes       []SizeProfile
+}
+
+// SizeProfile is a frame size and the intervals between frames the device
+// offers at it. For devices with a range of intervals rather than a list
+// only the shortest and longest are given.
+type SizeProfile struct {
+	Width, Height int
+	Intervals     []time.Duration
+}
+
+// FPS returns the frame rates of the size's intervals.
+func (s SizeProfile) FPS() []float64 {
+
+	fps := make([]float64, 0, len(s.Intervals))
+	for _, d := range s.Intervals {
+		if d > 0 {
+			fps = append(fps, float64(time.Second)/float64(d))
+		}
+	}
+
+	return fps
+}
+
+// DeviceProfile walks the formats, frame sizes and frame intervals the
+// device enumerates, for populating a camera picker. The walk takes an
+// ioctl per entry, so the result is kept and returned again by later
+// calls. For devices with a range of sizes rather than a list only the
+// smallest and largest are given.
+func (dev *handle) DeviceProfile() (DeviceProfile, error) {
+
+	if dev.profile != nil {
+		return *dev.profile, nil
+	}
+
+	var p DeviceProfile
+
+	for i := uint32(0); ; i++ {
+
+		d := v4l2_fmtdesc{Index: i, Type: V4L2_BUF_TYPE_VIDEO_CAPTURE}
+		b := toBytes(d)
+
+		// The enumeration ioctls end their lists with EINVAL.
+		if err := dev.ioctl(VIDIOC_ENUM_FMT, toUintptr(b)); err != nil {
+			if errors.Is(err, syscall.EINVAL) {
+				break
+			}
+			return DeviceProfile{}, fmt.Errorf("Failed to enumerate formats: %v", err.Error())
+		}
+
+		if err := fromBytes(b, &d); err != nil {
+			return DeviceProfile{}, err
+		}
+
+		sizes, err := dev.frameSizes(Format(d.Pixelformat))
+		if err != nil {
+			return DeviceProfile{}, err
+		}
+
+		p.Formats = append(p.Formats, FormatProfile{
+			Format:      Format(d.Pixelformat),
+			Description: cString(d.Description[:]),
+			Flags:       d.Flags,
+			Sizes:       sizes,
+		})
+	}
+
+	dev.profile = &p
+
+	return p, nil
+}
+
+// chooseFormat returns the first of formats the device enumerates for
+// capture.
+func (dev *handle) chooseFormat(formats []Format) (Format, error) {
+
+	offered := map[Format]bool{}
+
+	for i := uint32(0); ; i++ {
+
+		d := v4l2_fmtdesc{Index: i, Type: V4L2_BUF_TYPE_VIDEO_CAPTURE}
+		b := toBytes(d)
+
+		if err := dev.ioctl(VIDIOC_ENUM_FMT, toUintptr(b)); err != nil {
+			if errors.Is(err, syscall.EINVAL) {
+				break
+			}
+			return 0, fmt.Errorf("Failed to enumerate formats: %v", err.Error())
+		}
+
+		if err := fromBytes(b, &d); err != nil {
+			return 0, err
+		}
+
+		offered[Format(d.Pixelformat)] = true
+	}
+
+	for _, f := range formats {
+		if offered[f] {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Device offers none of %v", formats)
+}
+
+// enumFrameSizes returns the entries ENUM_FRAMESIZES lists for format: a
+// list of discrete sizes, or a single range.
+func (dev *handle) enumFrameSizes(format Format) ([]v4l2_frmsizeenum, error) {
+
+	var sizes []v4l2_frmsizeenum
+
+	for i := uint32(0); ; i++ {
+
+		s := v4l2_frmsizeenum{Index: i, PixelFormat: uint32(format)}
+		b := toBytes(s)
+
+		if err := dev.ioctl(VIDIOC_ENUM_FRAMESIZES, toUintptr(b)); err != nil {
+			if errors.Is(err, syscall.EINVAL) {
+				break
+			}
+			return nil, fmt.Errorf("Failed to enumerate frame sizes: %v", err.Error())
+		}
+
+		if err := fromBytes(b, &s); err != nil {
+			return nil, err
+		}
+
+		sizes = append(sizes, s)
+
+		if s.Type != V4L2_FRMSIZE_TYPE_DISCRETE {
+			break
+		}
+	}
+
+	return sizes, nil
+}
+
+func (dev *handle) frameSizes(format Format) ([]SizeProfile, error) {
+
+	entries, err := dev.enumFrameSizes(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var sizes []SizeProfile
+
+	add := func(w, h uint32) error {
+
+		intervals, err := dev.frameIntervals(format, w, h)
+		if err != nil {
+			return err
+		}
+
+		sizes = append(sizes, SizeProfile{Width: int(w), Height: int(h), Intervals: intervals})
+
+		return nil
+	}
+
+	for _, s := range entries {
+
+		if s.Type == V4L2_FRMSIZE_TYPE_DISCRETE {
+			if err := add(s.Union[0], s.Union[1]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// min_width, max_width, step_width, min_height, max_height,
+		// step_height. The range is the only entry.
+		if err := add(s.Union[0], s.Union[3]); err != nil {
+			return nil, err
+		}
+		if err := add(s.Union[1], s.Union[4]); err != nil {
+			return nil, err
+		}
+	}
+
+	return sizes, nil
+}
+
+// pickSize chooses the frame size to ask for as WithMinimumSize describes.
+// When the device enumerates no sizes, or a range rather than a list, the
+// request is left for the driver to adjust.
+func (dev *handle) pickSize(format Format, width, height, minWidth, minHeight int) (int, int) {
+
+	entries, err := dev.enumFrameSizes(format)
+	if err != nil {
+		return width, height
+	}
+
+	var below, above [2]int
+
+	for _, s := range entries {
+
+		if s.Type != V4L2_FRMSIZE_TYPE_DISCRETE {
+			return width, height
+		}
+
+		w, h := int(s.Union[0]), int(s.Union[1])
+
+		if w == width && h == height {
+			return width, height
+		}
+
+		if w < minWidth || h < minHeight {
+			continue
+		}
+
+		if w <= width && h <= height {
+			if w*h > below[0]*below[1] {
+				below = [2]int{w, h}
+			}
+		} else if above[0] == 0 || w*h < above[0]*above[1] {
+			above = [2]int{w, h}
+		}
+	}
+
+	if below[0] != 0 {
+		return below[0], below[1]
+	}
+	if above[0] != 0 {
+		return above[0], above[1]
+	}
+
+	return width, height
+}
+
+func (dev *handle) frameIntervals(format Format, width, height uint32) ([]time.Duration, error) {
+
+	var intervals []time.Duration
+
+	for i := uint32(0); ; i++ {
+
+		f := v4l2_frmivalenum{Index: i, PixelFormat: uint32(format), Width: width, Height: height}
+		b := toBytes(f)
+
+		if err := dev.ioctl(VIDIOC_ENUM_FRAMEINTERVALS, toUintptr(b)); err != nil {
+			if errors.Is(err, syscall.EINVAL) {
+				break
+			}
+			return nil, fmt.Errorf("Failed to enumerate frame intervals: %v", err.Error())
+		}
+
+		if err := fromBytes(b, &f); err != nil {
+			return nil, err
+		}
+
+		if f.Type == V4L2_FRMIVAL_TYPE_DISCRETE {
+			intervals = append(intervals, fractDuration(f.Union[0]))
+			continue
+		}
+
+		// min, max and step. The range is the only entry.
+		intervals = append(intervals, fractDuration(f.Union[0]), fractDuration(f.Union[1]))
+
+		break
+	}
+
+	return intervals, nil
+}
+
+func fractDuration(f v4l2_fract) time.Duration {
+
+	if f.Denominator == 0 {
+		return 0
+	}
+
+	return time.Duration(f.Numerator) * time.Second / time.Duration(f.Denominator)
+}
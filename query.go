@@ -0,0 +1,63 @@
+package v4l
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// QueryDevice is a read-only handle for inspecting a device, even one that
+// another process is capturing from. It has no capture methods: the
+// capabilities, controls and formats can be queried but nothing streamed.
+type QueryDevice struct {
+	handle
+}
+
+// QueryOpen opens the device read-only and non-blocking without touching
+// its format or buffers.
+func QueryOpen(device string) (*QueryDevice, error) {
+	return queryOpen(device, systemBackend{})
+}
+
+// Clone opens a second, query-only handle to the device, so that controls
+// and the format can be read from one goroutine while another captures
+// without the two contending. Streaming stays with the original Device;
+// the clone must be closed on its own.
+func (dev *Device) Clone() (*QueryDevice, error) {
+	return queryOpen(dev.device, dev.sys)
+}
+
+func queryOpen(device string, sys Backend) (*QueryDevice, error) {
+
+	fd, err := sys.Open(device, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open device: %v", err.Error())
+	}
+
+	h := handle{device: device, fd: fd, sys: sys}
+
+	caps, err := h.queryCap()
+	if err != nil {
+		sys.Close(fd)
+		return nil, fmt.Errorf("Failed to query capabilities: %v", err.Error())
+	}
+
+	h.caps = caps
+
+	return &QueryDevice{h}, nil
+}
+
+func (dev *QueryDevice) Close() {
+	dev.sys.Close(dev.fd)
+}
+
+// Format reads back the capture format currently set on the device, which
+// may have been negotiated through another handle.
+func (dev *QueryDevice) Format() (Format, int, int, error) {
+
+	pix, err := dev.getFormat()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("Failed to get format: %v", err.Error())
+	}
+
+	return Format(pix.Pixelformat), int(pix.Width), int(pix.Height), nil
+}
@@ -0,0 +1,68 @@
+package v4l
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// v4l2_format with the format union left as raw bytes, for the buffer
+// types that do not carry a v4l2_pix_format.
+type v4l2_format_raw struct {
+	Type uint32
+	_    uint32
+	Raw  [200]uint8
+}
+
+// setRawFormat selects format on an SDR or metadata device, or on other
+// types keeps the current format, and returns the size of one buffer. A
+// zero format keeps the SDR or metadata device's current format too.
+func (dev *handle) setRawFormat(bufType uint32, format Format) (int, error) {
+
+	f := v4l2_format_raw{Type: bufType}
+
+	req := uintptr(VIDIOC_G_FMT)
+	if (bufType == V4L2_BUF_TYPE_SDR_CAPTURE || bufType == V4L2_BUF_TYPE_META_CAPTURE) && format != 0 {
+		binary.LittleEndian.PutUint32(f.Raw[0:], uint32(format))
+		req = VIDIOC_S_FMT
+	}
+
+	b := toBytes(f)
+
+	if err := dev.ioctl(req, toUintptr(b)); err != nil {
+		return 0, err
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return 0, err
+	}
+
+	switch bufType {
+
+	case V4L2_BUF_TYPE_SDR_CAPTURE, V4L2_BUF_TYPE_META_CAPTURE:
+		// v4l2_sdr_format and v4l2_meta_format both start with the data
+		// format and the buffer size.
+		return int(binary.LittleEndian.Uint32(f.Raw[4:])), nil
+
+	case V4L2_BUF_TYPE_VBI_CAPTURE:
+		// v4l2_vbi_format: samples_per_line at 8, count[2] at 24.
+		samples := binary.LittleEndian.Uint32(f.Raw[8:])
+		lines := binary.LittleEndian.Uint32(f.Raw[24:]) + binary.LittleEndian.Uint32(f.Raw[28:])
+		return int(samples * lines), nil
+	}
+
+	return 0, fmt.Errorf("Unsupported buffer type: %d", bufType)
+}
+
+// GetMetadata captures the metadata buffer a V4L2_BUF_TYPE_META_CAPTURE
+// device delivers alongside each frame on its video node, such as the
+// exposure and gain the frame was taken with. The bytes are returned as
+// the driver wrote them; their layout depends on the metadata format,
+// which FrameInfo's sequence number ties to the matching video frame.
+func (dev *Device) GetMetadata() ([]byte, FrameInfo, error) {
+
+	if dev.bufType != V4L2_BUF_TYPE_META_CAPTURE {
+		return nil, FrameInfo{}, fmt.Errorf("Buffer type %d holds no metadata", dev.bufType)
+	}
+
+	return dev.GetRawFrame()
+}
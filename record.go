@@ -0,0 +1,92 @@
+package v4l
+
+import (
+	"context"
+	"io"
+)
+
+// RecordRaw captures n raw frames, or frames until ctx is done when n is
+// not positive, and writes them to w back to back as GetRawFrame returns
+// them, for muxing or converting later, for example with
+// `ffmpeg -f rawvideo -pix_fmt yuyv422 -s WxH -i`. Uncompressed frames are
+// written with their lines back to back, without the padding drivers may
+// add after each, as raw video readers expect; formats whose layout the
+// package does not know are written whole. Compressed frames are written
+// at the length the driver filled in, so they vary in size. It
+// returns the number of frames and bytes written. Stopping because ctx is
+// done is not an error.
+func (dev *Device) RecordRaw(ctx context.Context, w io.Writer, n int) (int, int64, error) {
+
+	// The recording owns the queue, which keeps format changes out.
+	dev.beginStream()
+	defer dev.endStream()
+
+	var frames int
+	var written int64
+	var buf, packed []byte
+
+	for n <= 0 || frames < n {
+
+		if len(buf) != dev.frameSize() {
+			buf = frameBuffer(dev.frameSize())
+		}
+
+		info, err := dev.captureContext(ctx, buf)
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return frames, written, nil
+		}
+		if err == errSourceChanged {
+			continue
+		}
+		if err != nil {
+			return frames, written, err
+		}
+
+		frame := buf
+		if dev.format.Compressed() {
+			if info.Bytesused != 0 && int(info.Bytesused) < len(buf) {
+				frame = buf[:info.Bytesused]
+			}
+		} else if packedLine(dev.format, dev.width) != 0 {
+			packed = dev.packLines(packed, buf)
+			frame = packed
+		}
+
+		m, err := w.Write(frame)
+		written += int64(m)
+		if err != nil {
+			return frames, written, err
+		}
+
+		frames++
+	}
+
+	return frames, written, nil
+}
+
+// packLines copies the lines of frame into out back to back, dropping the
+// padding after each line, and returns them. The chroma lines of the
+// semi-planar formats follow the luma lines. out is reused when it is
+// large enough.
+func (dev *Device) packLines(out, frame []byte) []byte {
+
+	line, stride := packedLine(dev.format, dev.width), dev.stride()
+
+	lines := dev.height
+	if semiPlanar(dev.format) {
+		lines += (dev.height + 1) / 2
+	}
+
+	frame = padFrame(frame, stride*lines)
+
+	if cap(out) < line*lines {
+		out = make([]byte, line*lines)
+	}
+	out = out[:line*lines]
+
+	for y := 0; y < lines; y++ {
+		copy(out[y*line:(y+1)*line], frame[y*stride:])
+	}
+
+	return out
+}
@@ -0,0 +1,19 @@
+package v4l
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPackLines(t *testing.T) {
+
+	const width, height = 10, 6
+
+	dev := &Device{format: V4L2_PIX_FMT_NV12, width: width, height: height}
+	dev.pix.Bytesperline = 16
+
+	got := dev.packLines(nil, nv12Frame(width, height, 16))
+	if want := nv12Frame(width, height, width); !bytes.Equal(got, want) {
+		t.Errorf("packed lines differ from the unpadded frame")
+	}
+}
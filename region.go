@@ -0,0 +1,127 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// GetFrameRegion captures a frame and converts only the pixels inside r,
+// given in capture coordinates, returning them as an image of r's size
+// anchored at the origin. Scale and transform settings do not apply.
+func (dev *Device) GetFrameRegion(r image.Rectangle) (*image.RGBA, error) {
+
+	if r.Empty() || !r.In(image.Rect(0, 0, dev.width, dev.height)) {
+		return nil, fmt.Errorf("Region %v outside of %dx%d frame", r, dev.width, dev.height)
+	}
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, err
+	}
+
+	frame, _, err := dev.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.In(image.Rect(0, 0, dev.width, dev.height)) {
+		return nil, fmt.Errorf("Region %v outside of %dx%d frame", r, dev.width, dev.height)
+	}
+
+	im := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+
+	if !regionFormat(dev.format) {
+		full := image.NewRGBA(image.Rect(0, 0, dev.width, dev.height))
+		lookupConverter(dev.format)(frame, dev.width, dev.height, dev.stride(), 1, full)
+		for y := 0; y < r.Dy(); y++ {
+			copy(im.Pix[y*im.Stride:(y+1)*im.Stride], full.Pix[full.PixOffset(r.Min.X, r.Min.Y+y):])
+		}
+		return im, nil
+	}
+
+	regionToImage(frame, dev.format, dev.width, dev.height, dev.stride(), r, im)
+
+	return im, nil
+}
+
+// regionFormat reports whether regionToImage can convert part of a frame
+// of format directly. Other formats, such as those with a registered
+// converter, are converted whole and then cropped.
+func regionFormat(format Format) bool {
+
+	if _, ok := bayer10Pattern(format); ok {
+		return true
+	}
+
+	if _, ok := packed422Layouts[format]; ok {
+		return true
+	}
+
+	switch format {
+	case V4L2_PIX_FMT_NV12, V4L2_PIX_FMT_NV21, V4L2_PIX_FMT_RGBA32, V4L2_PIX_FMT_Y210:
+		return true
+	}
+
+	return false
+}
+
+func regionToImage(frame []byte, format Format, width, height, stride int, r image.Rectangle, im *image.RGBA) {
+
+	if pattern, ok := bayer10Pattern(format); ok {
+		demosaic(func(x, y int) uint16 {
+			return bayer10Sample(frame, stride, r.Min.X+x, r.Min.Y+y)
+		}, r.Dx(), r.Dy(), 1, shiftPattern(pattern, r.Min.X, r.Min.Y), 10, im)
+		return
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+
+		row := frame[y*stride:]
+		p := (y - r.Min.Y) * im.Stride
+
+		switch format {
+
+		case V4L2_PIX_FMT_RGBA32:
+			copy(im.Pix[p:p+r.Dx()*4], row[r.Min.X*4:])
+			for i := p + 3; i < p+r.Dx()*4; i += 4 {
+				im.Pix[i] = 0xff
+			}
+
+		case V4L2_PIX_FMT_NV12, V4L2_PIX_FMT_NV21:
+			for x := r.Min.X; x < r.Max.X; x++ {
+				im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
+					nv12Sample(frame, stride, height, x, y, format == V4L2_PIX_FMT_NV21))
+				im.Pix[p+3] = 0xff
+				p += 4
+			}
+
+		case V4L2_PIX_FMT_Y210:
+			for x := r.Min.X; x < r.Max.X; x++ {
+				ys, cb, cr := y210Sample(row, width, x, 0)
+				im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
+					uint8(ys>>2),
+					uint8(cb>>2),
+					uint8(cr>>2))
+				im.Pix[p+3] = 0xff
+				p += 4
+			}
+
+		default:
+			l := packed422Layouts[format]
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i := (x / 2) * 4
+				y := l.y0
+				if x%2 == 1 {
+					y = l.y1
+				}
+				im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
+					row[i+y],
+					row[i+l.cb],
+					row[i+l.cr])
+				im.Pix[p+3] = 0xff
+				p += 4
+			}
+		}
+	}
+
+}
@@ -0,0 +1,48 @@
+package v4l
+
+import (
+	"errors"
+	"image"
+	"syscall"
+	"time"
+)
+
+// retryBackoff is how long GetFrameRetry waits before its first retry. The
+// wait doubles for each further one.
+const retryBackoff = 10 * time.Millisecond
+
+// GetFrameRetry captures a frame like GetFrame, making up to attempts
+// attempts while capture fails with EIO or EINTR, which some USB cameras
+// return for a single frame now and then. Because videobuf2 drivers refuse
+// every dequeue after an EIO until streaming restarts, the stream is
+// flushed before retrying one. Other errors, such as ENODEV when the
+// camera is unplugged, are returned at once. Stats counts the retries.
+// GetFrame itself never retries.
+func (dev *Device) GetFrameRetry(attempts int) (*image.RGBA, error) {
+
+	delay := retryBackoff
+
+	for i := 1; ; i++ {
+
+		im, err := dev.GetFrame()
+		if err == nil || i >= attempts || !transientError(err) {
+			return im, err
+		}
+
+		dev.logf("Retrying capture after: %v", err)
+		dev.stats.recordRetry()
+
+		time.Sleep(delay)
+		delay *= 2
+
+		if errors.Is(err, syscall.EIO) {
+			if err := dev.Flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func transientError(err error) bool {
+	return errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EINTR)
+}
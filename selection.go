@@ -0,0 +1,103 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+)
+
+const (
+	V4L2_SEL_TGT_CROP            uint32 = 0x0000
+	V4L2_SEL_TGT_CROP_DEFAULT           = 0x0001
+	V4L2_SEL_TGT_CROP_BOUNDS            = 0x0002
+	V4L2_SEL_TGT_NATIVE_SIZE            = 0x0003
+	V4L2_SEL_TGT_COMPOSE                = 0x0100
+	V4L2_SEL_TGT_COMPOSE_DEFAULT        = 0x0101
+	V4L2_SEL_TGT_COMPOSE_BOUNDS         = 0x0102
+	V4L2_SEL_TGT_COMPOSE_PADDED         = 0x0103
+
+	VIDIOC_G_SELECTION uintptr = 0xC040565E
+	VIDIOC_S_SELECTION         = 0xC040565F
+)
+
+type v4l2_rect struct {
+	Left, Top     int32
+	Width, Height uint32
+}
+
+type v4l2_selection struct {
+	Type, Target, Flags uint32
+	R                   v4l2_rect
+	Reserved            [9]uint32
+}
+
+func (r v4l2_rect) rectangle() image.Rectangle {
+	return image.Rect(int(r.Left), int(r.Top), int(r.Left)+int(r.Width), int(r.Top)+int(r.Height))
+}
+
+func rect(r image.Rectangle) v4l2_rect {
+	return v4l2_rect{Left: int32(r.Min.X), Top: int32(r.Min.Y), Width: uint32(r.Dx()), Height: uint32(r.Dy())}
+}
+
+func (dev *handle) getSelection(bufType, target uint32) (image.Rectangle, error) {
+
+	s := v4l2_selection{Type: bufType, Target: target}
+	b := toBytes(s)
+
+	if err := dev.ioctl(VIDIOC_G_SELECTION, toUintptr(b)); err != nil {
+		return image.Rectangle{}, fmt.Errorf("Failed to get selection: %w", err)
+	}
+
+	if err := fromBytes(b, &s); err != nil {
+		return image.Rectangle{}, err
+	}
+
+	return s.R.rectangle(), nil
+}
+
+// setSelection sets target to r and returns the rectangle the driver
+// adjusted it to.
+func (dev *handle) setSelection(bufType, target uint32, r image.Rectangle) (image.Rectangle, error) {
+
+	s := v4l2_selection{Type: bufType, Target: target, R: rect(r)}
+	b := toBytes(s)
+
+	if err := dev.ioctl(VIDIOC_S_SELECTION, toUintptr(b)); err != nil {
+		return image.Rectangle{}, fmt.Errorf("Failed to set selection: %w", err)
+	}
+
+	if err := fromBytes(b, &s); err != nil {
+		return image.Rectangle{}, err
+	}
+
+	return s.R.rectangle(), nil
+}
+
+// Selection returns a selection rectangle of the output, such as
+// V4L2_SEL_TGT_COMPOSE, where written frames are placed on the display,
+// or V4L2_SEL_TGT_COMPOSE_BOUNDS, the area they may be placed in.
+func (dev *OutputDevice) Selection(target uint32) (image.Rectangle, error) {
+	return dev.getSelection(V4L2_BUF_TYPE_VIDEO_OUTPUT, target)
+}
+
+// SetSelection sets a selection rectangle of the output. With
+// V4L2_SEL_TGT_COMPOSE it places each frame at r within the output, for
+// letterboxing or positioning a smaller frame on a larger canvas; r must
+// lie within V4L2_SEL_TGT_COMPOSE_BOUNDS. V4L2_SEL_TGT_CROP instead picks
+// the part of each frame that is shown. The driver may round r; the
+// rectangle it settled on is returned.
+func (dev *OutputDevice) SetSelection(target uint32, r image.Rectangle) (image.Rectangle, error) {
+
+	if target == V4L2_SEL_TGT_COMPOSE {
+
+		bounds, err := dev.getSelection(V4L2_BUF_TYPE_VIDEO_OUTPUT, V4L2_SEL_TGT_COMPOSE_BOUNDS)
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+
+		if r.Empty() || !r.In(bounds) {
+			return image.Rectangle{}, fmt.Errorf("Compose rectangle %v outside of bounds %v", r, bounds)
+		}
+	}
+
+	return dev.setSelection(V4L2_BUF_TYPE_VIDEO_OUTPUT, target, r)
+}
@@ -0,0 +1,46 @@
+package v4l
+
+import (
+	"bytes"
+	"image/jpeg"
+	"sync"
+)
+
+// snapshotBuffers are reused between snapshots, whose encoded size varies
+// little from one to the next.
+var snapshotBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// Snapshot captures a frame and returns it as a JPEG image, for serving a
+// still from an HTTP handler without a temporary file. A device capturing
+// V4L2_PIX_FMT_MJPEG returns the camera's own JPEG as it is, without
+// decoding and encoding it again, unless a scale, transform or LUT has to
+// be applied; quality then does not apply. Otherwise the frame GetFrame
+// returns is encoded at quality, from 1 to 100.
+func (dev *Device) Snapshot(quality int) ([]byte, error) {
+
+	if dev.format == V4L2_PIX_FMT_MJPEG && dev.scale == 1 && dev.transform == Identity && dev.lut == nil {
+
+		frame, _, err := dev.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		return jpegFrame(frame), nil
+	}
+
+	im, err := dev.GetFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := snapshotBuffers.Get().(*bytes.Buffer)
+	defer snapshotBuffers.Put(buf)
+
+	buf.Reset()
+
+	if err := jpeg.Encode(buf, im, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), buf.Bytes()...), nil
+}
@@ -0,0 +1,124 @@
+package v4l
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats counts the frames a Device has dequeued since Open or ResetStats.
+// Dropped frames are inferred from gaps in the driver's sequence numbers,
+// so they are frames lost before reaching the package.
+type Stats struct {
+	FramesCaptured  uint64
+	FramesDropped   uint64
+	FramesWithError uint64
+
+	// Retries counts the captures GetFrameRetry repeated after transient
+	// errors.
+	Retries uint64
+
+	// With SetTiming on, WaitTime totals how long frames spent queued until
+	// the driver handed them back, and ConvertTime how long converting
+	// FramesConverted of them to images took. Dividing by the frame counts
+	// tells whether the driver or the conversion limits the frame rate.
+	WaitTime        time.Duration
+	ConvertTime     time.Duration
+	FramesConverted uint64
+}
+
+type frameStats struct {
+	mu       sync.Mutex
+	stats    Stats
+	sequence uint32
+	seen     bool
+	queued   time.Time
+}
+
+func (s *frameStats) record(info FrameInfo) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.FramesCaptured++
+
+	if info.Error() {
+		s.stats.FramesWithError++
+	}
+
+	if s.seen && info.Sequence > s.sequence+1 {
+		s.stats.FramesDropped += uint64(info.Sequence - s.sequence - 1)
+	}
+
+	s.sequence = info.Sequence
+	s.seen = true
+}
+
+func (s *frameStats) recordRetry() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Retries++
+}
+
+// queue notes when a frame was queued, for recordWait.
+func (s *frameStats) queue() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued = time.Now()
+}
+
+func (s *frameStats) recordWait() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.queued.IsZero() {
+		s.stats.WaitTime += time.Since(s.queued)
+		s.queued = time.Time{}
+	}
+}
+
+// recordRead counts the time a read() took as the wait for its frame.
+func (s *frameStats) recordRead(start time.Time) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.WaitTime += time.Since(start)
+}
+
+func (s *frameStats) recordConvert(start time.Time) {
+
+	d := time.Since(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.ConvertTime += d
+	s.stats.FramesConverted++
+}
+
+// SetTiming makes Stats measure WaitTime and ConvertTime. It is off by
+// default, so that capture does not read the clock.
+func (dev *Device) SetTiming(timing bool) {
+	dev.timing = timing
+}
+
+func (dev *Device) Stats() Stats {
+
+	dev.stats.mu.Lock()
+	defer dev.stats.mu.Unlock()
+
+	return dev.stats.stats
+}
+
+func (dev *Device) ResetStats() {
+
+	dev.stats.mu.Lock()
+	defer dev.stats.mu.Unlock()
+
+	dev.stats.stats = Stats{}
+}
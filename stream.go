@@ -0,0 +1,328 @@
+package v4l
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+// DropPolicy decides what a Stream does with a frame when the consumer has
+// not yet taken the previous one.
+type DropPolicy int
+
+const (
+	// Block waits for the consumer before capturing the next frame.
+	Block DropPolicy = iota
+	// DropOldest replaces the pending frame so the newest is delivered.
+	DropOldest
+	// DropNewest discards the new frame and keeps the pending one.
+	DropNewest
+)
+
+type streamConfig struct {
+	policy     DropPolicy
+	interval   time.Duration
+	skipErrors bool
+	motion     *MotionDetector
+}
+
+type StreamOption func(*streamConfig)
+
+func WithDropPolicy(policy DropPolicy) StreamOption {
+	return func(c *streamConfig) {
+		c.policy = policy
+	}
+}
+
+// WithMaxFPS limits a stream to n frames per second. Frames the camera
+// delivers in between are dequeued and discarded without being converted.
+func WithMaxFPS(n int) StreamOption {
+	return func(c *streamConfig) {
+		if n > 0 {
+			c.interval = time.Second / time.Duration(n)
+		}
+	}
+}
+
+// WithSkipErrorFrames keeps a stream going past frames the driver flagged
+// as corrupt, dropping them and waiting for the next good one, where it
+// would otherwise end, whether or not the device is strict.
+func WithSkipErrorFrames() StreamOption {
+	return func(c *streamConfig) {
+		c.skipErrors = true
+	}
+}
+
+// WithMotionFilter makes a stream deliver only frames in which d detects
+// motion. Frames of packed 4:2:2 and NV12 formats are compared on their
+// luma and converted only when they pass; other formats are converted
+// first.
+func WithMotionFilter(d *MotionDetector) StreamOption {
+	return func(c *streamConfig) {
+		c.motion = d
+	}
+}
+
+// Stream delivers frames captured by a background goroutine on C. C is
+// closed when the stream is stopped or capturing fails.
+type Stream struct {
+	C <-chan *image.RGBA
+
+	c    chan *image.RGBA
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// SetFrameCallback makes Stream pass each raw frame to fn instead of
+// converting it and sending it on the stream's channel. buf is reused for
+// the next frame as soon as fn returns, so fn must copy anything it keeps.
+// A nil fn restores normal streaming. It takes effect for streams started
+// afterwards.
+func (dev *Device) SetFrameCallback(fn func(buf []byte, info FrameInfo)) {
+	dev.callback = fn
+}
+
+func (dev *Device) Stream(opts ...StreamOption) *Stream {
+
+	var config streamConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	c := make(chan *image.RGBA, 1)
+
+	s := &Stream{
+		C:    c,
+		c:    c,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	dev.beginStream()
+	go s.run(dev, config)
+
+	return s
+}
+
+// Stop ends the stream, waiting for the capture in progress to finish, and
+// returns the error that ended the stream early, if any.
+func (s *Stream) Stop() error {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+	return s.err
+}
+
+func (s *Stream) run(dev *Device, config streamConfig) {
+
+	defer close(s.done)
+	defer close(s.c)
+	defer dev.endStream()
+
+	limit := newRateLimit(config.interval)
+	defer limit.stop()
+
+	if cb := dev.callback; cb != nil {
+		s.runCallback(dev, cb, limit, config.skipErrors)
+		return
+	}
+
+	var skip []byte
+
+	for {
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		if !limit.ready() {
+
+			dev.mu.Lock()
+			bg := dev.background
+			dev.mu.Unlock()
+
+			// With background capture on its goroutine owns the queue, so
+			// there is nothing to discard and the stream just waits.
+			if bg != nil {
+				if !limit.wait(s.stop) {
+					return
+				}
+				continue
+			}
+
+			if len(skip) != dev.frameSize() {
+				skip = frameBuffer(dev.frameSize())
+			}
+
+			if _, err := dev.readFrameInto(skip); err != nil && err != errSourceChanged && err != ErrFrameCorrupt {
+				s.err = err
+				return
+			}
+			continue
+		}
+
+		var im *image.RGBA
+		var err error
+
+		if config.motion != nil {
+			var moved bool
+			im, moved, err = dev.motionFrame(config.motion, config.skipErrors)
+			if err == nil && !moved {
+				continue
+			}
+		} else {
+			var info FrameInfo
+			im, info, err = dev.getFrame()
+			if err == nil && config.skipErrors && info.Error() {
+				err = ErrFrameCorrupt
+			}
+		}
+
+		if err == ErrFrameCorrupt && config.skipErrors {
+			continue
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		if !s.deliver(im, config.policy) {
+			return
+		}
+	}
+}
+
+// runCallback hands every frame to the device's frame callback in a single
+// reused buffer instead of converting it and sending it on C.
+func (s *Stream) runCallback(dev *Device, cb func([]byte, FrameInfo), limit *rateLimit, skipErrors bool) {
+
+	frame := frameBuffer(dev.frameSize())
+
+	for {
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		info, err := dev.readFrameInto(frame)
+		if err == errSourceChanged {
+			frame = frameBuffer(dev.frameSize())
+			continue
+		}
+		if err == nil && skipErrors && info.Error() {
+			err = ErrFrameCorrupt
+		}
+		if err == ErrFrameCorrupt && skipErrors {
+			continue
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		if !limit.ready() {
+			continue
+		}
+
+		buf := frame
+		if int(info.Bytesused) < len(buf) {
+			buf = buf[:info.Bytesused]
+		}
+
+		cb(buf, info)
+	}
+}
+
+func (s *Stream) deliver(im *image.RGBA, policy DropPolicy) bool {
+
+	switch policy {
+
+	case DropNewest:
+		select {
+		case s.c <- im:
+		default:
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case s.c <- im:
+				return true
+			default:
+			}
+
+			select {
+			case <-s.c:
+			default:
+			}
+		}
+
+	default:
+		select {
+		case s.c <- im:
+		case <-s.stop:
+			return false
+		}
+	}
+
+	return true
+}
+
+// rateLimit lets one frame through per tick of its ticker. The first frame
+// passes immediately. A zero interval lets every frame through.
+type rateLimit struct {
+	ticker *time.Ticker
+	due    bool
+}
+
+func newRateLimit(interval time.Duration) *rateLimit {
+
+	if interval <= 0 {
+		return &rateLimit{}
+	}
+
+	return &rateLimit{ticker: time.NewTicker(interval), due: true}
+}
+
+func (r *rateLimit) ready() bool {
+
+	if r.ticker == nil {
+		return true
+	}
+
+	select {
+	case <-r.ticker.C:
+		r.due = true
+	default:
+	}
+
+	if !r.due {
+		return false
+	}
+
+	r.due = false
+	return true
+}
+
+// wait blocks until the next tick, returning false if stop closes first.
+func (r *rateLimit) wait(stop <-chan struct{}) bool {
+
+	select {
+	case <-r.ticker.C:
+		r.due = true
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+func (r *rateLimit) stop() {
+	if r.ticker != nil {
+		r.ticker.Stop()
+	}
+}
@@ -0,0 +1,252 @@
+package v4l
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// StreamEventType says what a StreamEvent reports.
+type StreamEventType int
+
+const (
+	// SourceChanged reports that the source switched resolution. Width and
+	// Height give the new one.
+	SourceChanged StreamEventType = iota
+	// Disconnected reports that the device went away. Err holds the error
+	// capture failed with; the stream keeps trying to reopen the device.
+	Disconnected
+	// Reconnected reports that the device was reopened and its controls
+	// restored after a Disconnected event.
+	Reconnected
+	// StreamFailed reports the error that ended the stream, or a failure to
+	// restore controls after reconnecting, which does not.
+	StreamFailed
+)
+
+// StreamEvent is a notification from StreamContext.
+type StreamEvent struct {
+	Type          StreamEventType
+	Err           error
+	Width, Height int
+}
+
+// streamPollInterval bounds how long StreamContext waits on the driver
+// before checking whether its context is done.
+const streamPollInterval = 100 * time.Millisecond
+
+// reconnectDelay is how long StreamContext waits between attempts to
+// reopen a device that went away.
+const reconnectDelay = time.Second
+
+// StreamContext captures frames on a background goroutine until ctx is
+// done, delivering them on the first channel and notifications about the
+// stream on the second. When the device goes away, as a USB camera does
+// when it is unplugged, it is reopened with the same format and the
+// controls it had when the stream started. Each Frame must be released
+// once the consumer is done with it. Events are dropped when nobody reads
+// them, so they never hold up frames. Both channels are closed when the
+// stream ends, either because ctx is done or because capture failed with
+// an error other than the device going away. The stream owns the device's
+// buffer queue while it runs, so the device must not be captured from
+// otherwise in the meantime.
+func (dev *Device) StreamContext(ctx context.Context, opts ...StreamOption) (<-chan *Frame, <-chan StreamEvent) {
+
+	var config streamConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	frames := make(chan *Frame, 1)
+	events := make(chan StreamEvent, 8)
+
+	dev.beginStream()
+	go dev.runContext(ctx, config, frames, events)
+
+	return frames, events
+}
+
+func (dev *Device) runContext(ctx context.Context, config streamConfig, frames chan *Frame, events chan StreamEvent) {
+
+	defer close(events)
+	defer close(frames)
+	defer dev.endStream()
+
+	limit := newRateLimit(config.interval)
+	defer limit.stop()
+
+	notify := func(e StreamEvent) {
+		select {
+		case events <- e:
+		default:
+		}
+	}
+
+	saved, _ := dev.SaveControlState()
+
+	if err := dev.checkConvert(); err != nil {
+		notify(StreamEvent{Type: StreamFailed, Err: err})
+		return
+	}
+
+	for ctx.Err() == nil {
+
+		buf := dev.spareBuffer()
+
+		info, err := dev.captureContext(ctx, buf)
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			dev.putBuffer(buf)
+			return
+		}
+		if err == errSourceChanged {
+			notify(StreamEvent{Type: SourceChanged, Width: dev.width, Height: dev.height})
+			continue
+		}
+		if err == nil && config.skipErrors && info.Error() {
+			err = ErrFrameCorrupt
+		}
+		if err == ErrFrameCorrupt && config.skipErrors {
+			dev.putBuffer(buf)
+			continue
+		}
+		if errors.Is(err, syscall.ENODEV) {
+			notify(StreamEvent{Type: Disconnected, Err: err})
+			ok := dev.reconnect(ctx, saved, notify)
+			// Closing the vanished node took buf back from the driver.
+			dev.putBuffer(buf)
+			if !ok {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			dev.putBuffer(buf)
+			notify(StreamEvent{Type: StreamFailed, Err: err})
+			return
+		}
+
+		if !limit.ready() {
+			dev.putBuffer(buf)
+			continue
+		}
+
+		if !deliverFrame(ctx, frames, dev.newFrame(buf, info), config.policy) {
+			return
+		}
+	}
+}
+
+// captureContext reads a frame into buf like readFrameInto, but waits on
+// the driver in short polls so that it gives up once ctx is done. A frame
+// still queued then is taken back from the driver before returning.
+func (dev *Device) captureContext(ctx context.Context, buf []byte) (FrameInfo, error) {
+
+	if dev.Weaving() {
+		return dev.weaveInto(buf, func(field []byte) (FrameInfo, error) {
+			return dev.captureBuffer(ctx, field)
+		})
+	}
+
+	return dev.captureBuffer(ctx, buf)
+}
+
+// captureBuffer is captureContext for a single buffer of the driver's.
+func (dev *Device) captureBuffer(ctx context.Context, buf []byte) (FrameInfo, error) {
+
+	bqbuf, err := dev.queueFrame(buf)
+	if err != nil {
+		return FrameInfo{}, err
+	}
+
+	for {
+
+		_, err := dev.sys.Poll(dev.fd, POLLIN, streamPollInterval)
+		if err == ErrTimeout {
+			if ctx.Err() != nil {
+				dev.restartStream()
+				return FrameInfo{}, ctx.Err()
+			}
+			continue
+		}
+		if err != nil {
+			// buf is still queued; take it back before the caller reuses
+			// it.
+			dev.restartStream()
+			return FrameInfo{}, err
+		}
+
+		return dev.dequeueFrame(buf, bqbuf)
+	}
+}
+
+// reconnect closes the vanished device and reopens it until it succeeds or
+// ctx is done, then restores the saved controls. It reports whether the
+// device is open again.
+func (dev *Device) reconnect(ctx context.Context, saved ControlState, notify func(StreamEvent)) bool {
+
+	dev.closeNode()
+
+	for {
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(reconnectDelay):
+		}
+
+		if err := dev.reopen(); err != nil {
+			continue
+		}
+
+		notify(StreamEvent{Type: Reconnected, Width: dev.width, Height: dev.height})
+
+		if saved != nil {
+			if err := dev.RestoreControlState(saved); err != nil {
+				notify(StreamEvent{Type: StreamFailed, Err: err})
+			}
+		}
+
+		return true
+	}
+}
+
+// deliverFrame sends f on frames following policy, releasing any frame that
+// is dropped. It returns false if ctx is done first.
+func deliverFrame(ctx context.Context, frames chan *Frame, f *Frame, policy DropPolicy) bool {
+
+	switch policy {
+
+	case DropNewest:
+		select {
+		case frames <- f:
+		default:
+			f.Release()
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case frames <- f:
+				return true
+			default:
+			}
+
+			select {
+			case old := <-frames:
+				old.Release()
+			default:
+			}
+		}
+
+	default:
+		select {
+		case frames <- f:
+		case <-ctx.Done():
+			f.Release()
+			return false
+		}
+	}
+
+	return true
+}
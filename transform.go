@@ -0,0 +1,85 @@
+package v4l
+
+import (
+	"image"
+)
+
+// Transform is a rotation or mirror applied in software to every frame
+// returned by GetFrame, for devices without HFLIP/VFLIP controls.
+type Transform int
+
+const (
+	Identity Transform = iota
+	// Rotate90, Rotate180 and Rotate270 rotate clockwise. Rotate90 and
+	// Rotate270 swap the width and height of the returned image.
+	Rotate90
+	Rotate180
+	Rotate270
+	FlipH
+	FlipV
+)
+
+func (dev *Device) SetTransform(t Transform) {
+	dev.transform = t
+}
+
+// transformed applies the device's transform and overlay to im, the frame
+// described by info.
+func (dev *Device) transformed(im *image.RGBA, info FrameInfo) *image.RGBA {
+
+	if dev.transform == Identity {
+		dev.drawOverlay(im, info)
+		return im
+	}
+
+	dst := image.NewRGBA(transformBounds(im.Rect, dev.transform))
+	transformImage(im, dev.transform, dst)
+	dev.drawOverlay(dst, info)
+
+	return dst
+}
+
+func transformBounds(r image.Rectangle, t Transform) image.Rectangle {
+
+	if t == Rotate90 || t == Rotate270 {
+		return image.Rect(0, 0, r.Dy(), r.Dx())
+	}
+
+	return image.Rect(0, 0, r.Dx(), r.Dy())
+}
+
+// transformImage writes src into dst, which must have the bounds given by
+// transformBounds.
+func transformImage(src *image.RGBA, t Transform, dst *image.RGBA) {
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	for y := 0; y < h; y++ {
+
+		s := y * src.Stride
+
+		for x := 0; x < w; x++ {
+
+			var dx, dy int
+			switch t {
+			case Rotate90:
+				dx, dy = h-1-y, x
+			case Rotate180:
+				dx, dy = w-1-x, h-1-y
+			case Rotate270:
+				dx, dy = y, w-1-x
+			case FlipH:
+				dx, dy = w-1-x, y
+			case FlipV:
+				dx, dy = x, h-1-y
+			default:
+				dx, dy = x, y
+			}
+
+			d := dy*dst.Stride + dx*4
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+			s += 4
+		}
+	}
+
+}
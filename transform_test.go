@@ -0,0 +1,48 @@
+package v4l
+
+import (
+	"image"
+	"testing"
+)
+
+func TestTransformImage(t *testing.T) {
+
+	// abc
+	// def
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i, c := range "abcdef" {
+		src.Pix[i*4] = uint8(c)
+	}
+
+	for _, c := range []struct {
+		t    Transform
+		w    int
+		want string
+	}{
+		{Identity, 3, "abcdef"},
+		{Rotate90, 2, "daebfc"},
+		{Rotate180, 3, "fedcba"},
+		{Rotate270, 2, "cfbead"},
+		{FlipH, 3, "cbafed"},
+		{FlipV, 3, "defabc"},
+	} {
+
+		r := transformBounds(src.Rect, c.t)
+		if r.Dx() != c.w || r.Dx()*r.Dy() != 6 {
+			t.Errorf("transform %d: bounds %v", c.t, r)
+			continue
+		}
+
+		dst := image.NewRGBA(r)
+		transformImage(src, c.t, dst)
+
+		got := make([]byte, 6)
+		for i := range got {
+			got[i] = dst.Pix[i*4]
+		}
+
+		if string(got) != c.want {
+			t.Errorf("transform %d: got %s, want %s", c.t, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package v4l
+
+const (
+	V4L2_COLORFX_NONE         int32 = 0
+	V4L2_COLORFX_BW                 = 1
+	V4L2_COLORFX_SEPIA              = 2
+	V4L2_COLORFX_NEGATIVE           = 3
+	V4L2_COLORFX_EMBOSS             = 4
+	V4L2_COLORFX_SKETCH             = 5
+	V4L2_COLORFX_SKY_BLUE           = 6
+	V4L2_COLORFX_GRASS_GREEN        = 7
+	V4L2_COLORFX_SKIN_WHITEN        = 8
+	V4L2_COLORFX_VIVID              = 9
+	V4L2_COLORFX_AQUA               = 10
+	V4L2_COLORFX_ART_FREEZE         = 11
+	V4L2_COLORFX_SILHOUETTE         = 12
+	V4L2_COLORFX_SOLARIZATION       = 13
+	V4L2_COLORFX_ANTIQUE            = 14
+)
+
+func boolControl(v bool) int32 {
+
+	if v {
+		return 1
+	}
+
+	return 0
+}
+
+// SetHorizontalFlip mirrors the image in the sensor. Devices without the
+// control return ErrUnsupportedControl; mirroring in software is a
+// separate feature.
+func (dev *Device) SetHorizontalFlip(flip bool) error {
+	return dev.SetControl(V4L2_CID_HFLIP, boolControl(flip))
+}
+
+// SetVerticalFlip flips the image upside down in the sensor. Together with
+// SetHorizontalFlip this rotates by 180 degrees for upside down mounts.
+func (dev *Device) SetVerticalFlip(flip bool) error {
+	return dev.SetControl(V4L2_CID_VFLIP, boolControl(flip))
+}
+
+// SetColorEffect applies one of the V4L2_COLORFX effects in the sensor,
+// such as V4L2_COLORFX_SEPIA or V4L2_COLORFX_BW. Devices without the
+// control return ErrUnsupportedControl, and an effect the device does not
+// offer is an error.
+func (dev *Device) SetColorEffect(effect int32) error {
+	return dev.setMenuControl(V4L2_CID_COLORFX, effect)
+}
+
+// ColorEffect returns the V4L2_COLORFX effect the sensor applies.
+func (dev *Device) ColorEffect() (int32, error) {
+	return dev.GetControl(V4L2_CID_COLORFX)
+}
+
+// SetSharpness sets how much edge enhancement the camera applies, clamped
+// to the supported range. The lowest value usually turns it off, which
+// avoids the ringing it puts around text.
+func (dev *Device) SetSharpness(v int32) error {
+	return dev.setClamped(V4L2_CID_SHARPNESS, v)
+}
+
+// Sharpness returns the camera's current edge enhancement.
+func (dev *Device) Sharpness() (int32, error) {
+	return dev.GetControl(V4L2_CID_SHARPNESS)
+}
+
+// SetBacklightCompensation sets how strongly the camera brightens a subject
+// in front of a bright background, clamped to the supported range. Zero
+// turns it off.
+func (dev *Device) SetBacklightCompensation(v int32) error {
+	return dev.setClamped(V4L2_CID_BACKLIGHT_COMPENSATION, v)
+}
+
+// BacklightCompensation returns the camera's current backlight compensation.
+func (dev *Device) BacklightCompensation() (int32, error) {
+	return dev.GetControl(V4L2_CID_BACKLIGHT_COMPENSATION)
+}
+
+// SetAutoGain lets the camera adjust gain itself. While it is on the gain
+// control is inactive.
+func (dev *Device) SetAutoGain(auto bool) error {
+	return dev.SetControl(V4L2_CID_AUTOGAIN, boolControl(auto))
+}
+
+// AutoGain reports whether the camera adjusts gain itself.
+func (dev *Device) AutoGain() (bool, error) {
+
+	v, err := dev.GetControl(V4L2_CID_AUTOGAIN)
+	if err != nil {
+		return false, err
+	}
+
+	return v != 0, nil
+}
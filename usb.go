@@ -0,0 +1,75 @@
+package v4l
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const USBDEVFS_RESET uintptr = 0x5514
+
+// ResetDevice resets the USB device the camera is attached through, as if
+// it had been unplugged and plugged back in. It is a last resort for a
+// camera that has stopped delivering frames. The device disappears during
+// the reset, so it has to be closed and opened again afterwards.
+// Resetting needs write access to the node under /dev/bus/usb.
+func (dev *handle) ResetDevice() error {
+
+	usb, err := usbDevice(dev.device)
+	if err != nil {
+		return err
+	}
+
+	fd, err := syscall.Open(usb, syscall.O_WRONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("Failed to open %s: %v", usb, err.Error())
+	}
+	defer syscall.Close(fd)
+
+	if err := ioctl(fd, USBDEVFS_RESET, 0); err != nil {
+		return fmt.Errorf("Failed to reset %s: %v", usb, err.Error())
+	}
+
+	return nil
+}
+
+// usbDevice finds the /dev/bus/usb node of the USB device behind a video
+// node. In sysfs a video node's device link points at a USB interface,
+// whose parent directory is the USB device with its bus and device numbers.
+func usbDevice(device string) (string, error) {
+
+	if p, err := filepath.EvalSymlinks(device); err == nil {
+		device = p
+	}
+
+	link := filepath.Join("/sys/class/video4linux", filepath.Base(device), "device")
+
+	intf, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find %s in sysfs: %v", device, err.Error())
+	}
+
+	parent := filepath.Dir(intf)
+
+	busnum, err := os.ReadFile(filepath.Join(parent, "busnum"))
+	if err != nil {
+		return "", fmt.Errorf("Not a USB device: %s", device)
+	}
+
+	devnum, err := os.ReadFile(filepath.Join(parent, "devnum"))
+	if err != nil {
+		return "", fmt.Errorf("Not a USB device: %s", device)
+	}
+
+	var bus, num int
+	if _, err := fmt.Sscan(strings.TrimSpace(string(busnum)), &bus); err != nil {
+		return "", fmt.Errorf("Failed to parse bus number: %v", err.Error())
+	}
+	if _, err := fmt.Sscan(strings.TrimSpace(string(devnum)), &num); err != nil {
+		return "", fmt.Errorf("Failed to parse device number: %v", err.Error())
+	}
+
+	return fmt.Sprintf("/dev/bus/usb/%03d/%03d", bus, num), nil
+}
@@ -3,58 +3,89 @@ package v4l
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	"log"
 	"os"
 	"reflect"
+	"runtime"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
+var ErrDeviceBusy = errors.New("Device busy")
+
+// ErrDeviceClosed is returned to callers waiting on a capture goroutine
+// that stopped because the Device was closed.
+var ErrDeviceClosed = errors.New("Device closed")
+
+// ErrFrameCorrupt is returned for frames the driver flagged as unreliable
+// when the device is strict.
+var ErrFrameCorrupt = errors.New("Frame corrupt")
+
 var (
-	V4L2_PIX_FMT_YUYV  uint32 = 0x56595559
-	V4L2_PIX_FMT_RGB32 uint32 = 0x59565955
+	V4L2_PIX_FMT_YUYV   = FourCC("YUYV")
+	V4L2_PIX_FMT_UYVY   = FourCC("UYVY")
+	V4L2_PIX_FMT_YVYU   = FourCC("YVYU")
+	V4L2_PIX_FMT_VYUY   = FourCC("VYUY")
+	V4L2_PIX_FMT_NV12   = FourCC("NV12")
+	V4L2_PIX_FMT_NV21   = FourCC("NV21")
+	V4L2_PIX_FMT_RGB24  = FourCC("RGB3")
+	V4L2_PIX_FMT_BGR24  = FourCC("BGR3")
+	V4L2_PIX_FMT_RGB32  = FourCC("RGB4")
+	V4L2_PIX_FMT_RGBA32 = FourCC("AB24")
+	V4L2_PIX_FMT_Y210   = FourCC("Y210")
+	V4L2_PIX_FMT_MJPEG  = FourCC("MJPG")
+	V4L2_PIX_FMT_H264   = FourCC("H264")
 )
 
 const (
 	V4L2_BUF_TYPE_VIDEO_CAPTURE uint32 = 1
+	V4L2_BUF_TYPE_VBI_CAPTURE          = 4
+	V4L2_BUF_TYPE_SDR_CAPTURE          = 11
+	V4L2_BUF_TYPE_META_CAPTURE         = 13
 	V4L2_MEMORY_USERPTR                = 2
 
-	VIDIOC_S_FMT    uintptr = 0xC0D05605
+	VIDIOC_QUERYCAP uintptr = 0x80685600
+	VIDIOC_S_FMT            = 0xC0D05605
 	VIDIOC_G_FMT            = 0xC0D05604
 	VIDIOC_STREAMON         = 0x40045612
 	VIDIOC_REQBUFS          = 0xC0145608
 	VIDIOC_QBUF             = 0xC058560F
 	VIDIOC_DQBUF            = 0xC0585611
+
+	VIDIOC_PREPARE_BUF uintptr = 0xC058565D
 )
 
+type v4l2_format struct {
+	Type uint32
+	_    uint32 // the union below is 8-byte aligned on 64-bit
+
+	Pix v4l2_pix_format
+	_   [152]uint8 // rest of the 200 byte union
+}
+
 type v4l2_pix_format struct {
-	Type, Width, Height, Pixelformat, Field          uint32
+	Width, Height, Pixelformat, Field                uint32
 	Bytesperline, Sizeimage, Colorspace, Priv, Flags uint32
 	YCBCREnc, Quantization, XferFunc                 uint32
-	a                                                uint64
-	b                                                uint64
-	c                                                uint64
-	d                                                uint64
-	e                                                uint64
 }
 
 type v4l2_requestbuffers struct {
-	Count     uint32
-	Type      uint32
-	Memory    uint32
-	Reserved0 uint32
-	a         uint64
-	b         uint64
-	c         uint64
-	d         uint64
-	e         uint64
+	Count        uint32
+	Type         uint32
+	Memory       uint32
+	Capabilities uint32
+	Flags        uint8
+	Reserved     [3]uint8
 }
 
 type v4l2_buffer struct {
 	Index, Type, Bytesused, Flags, Field uint32
+	_                                    uint32
 
 	// timeval
 	TvSec, TvUsec uint64
@@ -63,156 +94,1487 @@ type v4l2_buffer struct {
 	TcType, TcFlags                                                             uint32
 	TcFrames, TcSeconds, TcMinutes, TcHours, TcUser0, TcUser1, TcUser2, TcUser3 uint8
 
-	padding0                    uint32
 	Sequence, Memory            uint32
 	Userptr                     uint64
 	Length, Reserved2, Reserved uint32
-	a                           uint64
-	b                           uint64
-	c                           uint64
-	d                           uint64
-	e                           uint64
+	_                           uint32
+}
+
+// handle is an open device node and what it reported about itself; it
+// carries the methods shared by Device and QueryDevice.
+type handle struct {
+	device  string
+	fd      int
+	sys     Backend
+	caps    v4l2_capability
+	timeout time.Duration
+	profile *DeviceProfile
+	logger  Logger
+
+	buffers   int
+	streaming bool
+
+	// readIO captures with read() instead of streaming, for devices
+	// without streaming I/O.
+	readIO bool
 }
 
 type Device struct {
-	device string
-	fd     int
-	width  int
-	height int
+	handle
+	pix        v4l2_pix_format
+	adjustment FormatAdjustment
+	format     Format
+	width      int
+	height     int
+	scale      int
+
+	parallel  bool
+	strict    bool
+	timing    bool
+	transform Transform
+	lut       *[256]uint8
+	overlay   *Overlay
+
+	latestOnce sync.Once
+
+	mu         sync.Mutex
+	background *backgroundCapture
+	latest     *latestFrame
+	ae         *autoExposure
+	watches    []*controlWatch
+
+	// streams counts the goroutines of Stream, StreamContext,
+	// PreEventBuffer and LatestFrame, which own the queue while they run;
+	// it is guarded by mu.
+	streams int
+
+	stats frameStats
+
+	spareMu sync.Mutex
+	spare   [][]byte
+
+	callback func([]byte, FrameInfo)
+
+	events bool
+	lock   *os.File
+	config config
+
+	bufType uint32
+	rawSize int
+
+	readSequence uint32
+
+	// shortSize is the Bytesused of frames that renegotiate found the
+	// format of shortPix unchanged for, so that drivers filling every frame
+	// short of Sizeimage are not asked for their format again each frame.
+	shortSize uint32
+	shortPix  v4l2_pix_format
+
+	// prepared is the address of the buffer last prepared with PREPARE_BUF,
+	// and noPrepare is set once the driver turned out not to implement it.
+	prepared  uint64
+	noPrepare bool
+
+	// bounce is the aligned buffer frames in unaligned memory are captured
+	// into; see bounceBuffer.
+	bounce []byte
+
+	// field is the buffer the fields of an alternating stream are captured
+	// into before being woven; see weaveInto.
+	field []byte
+
+	jpeg *jpegDecoder
 }
 
-func Open(device string, width, height int) (*Device, error) {
+func Open(device string, width, height int, opts ...Option) (*Device, error) {
 
-	fd, err := syscall.Open(device, os.O_RDWR|syscall.O_CLOEXEC, 0666)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to open device: %v", err.Error())
+	// Devices of other buffer types keep the data format they have, as
+	// YUYV means nothing to them.
+	format := V4L2_PIX_FMT_YUYV
+	if newConfig(opts).bufType != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		format = 0
 	}
 
-	if err := setFormat(fd, V4L2_PIX_FMT_YUYV, width, height); err != nil {
-		syscall.Close(fd)
-		return nil, fmt.Errorf("Failed to set format: %v", err.Error())
+	return OpenFormat(device, format, width, height, opts...)
+}
+
+// OpenFormat opens the device capturing in the given pixel format, which
+// must have a converter registered for GetFrame. V4L2_PIX_FMT_H264 is also
+// accepted for use with GetRawFrame.
+func OpenFormat(device string, format Format, width, height int, opts ...Option) (*Device, error) {
+
+	config := newConfig(opts)
+
+	for _, f := range append([]Format{format}, config.formats...) {
+		if config.bufType == V4L2_BUF_TYPE_VIDEO_CAPTURE && lookupConverter(f) == nil && !f.Compressed() {
+			return nil, fmt.Errorf("Unsupported format: %v", f)
+		}
 	}
 
-	if err := setUserptr(fd); err != nil {
-		syscall.Close(fd)
-		return nil, fmt.Errorf("Failed to set user space ptr: %v", err.Error())
+	dev, err := openConfig(device, format, width, height, config)
+	for i := 0; i < config.retries && errors.Is(err, ErrDeviceBusy); i++ {
+		time.Sleep(config.backoff)
+		dev, err = openConfig(device, format, width, height, config)
 	}
 
-	return &Device{device: device, fd: fd, width: width, height: height}, nil
+	return dev, err
 }
 
-func (dev *Device) Close() {
-	syscall.Close(dev.fd)
+func openConfig(device string, format Format, width, height int, config config) (*Device, error) {
+
+	if !config.exclusive {
+		return openDevice(device, format, width, height, config)
+	}
+
+	lock, err := lockDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	dev, err := openDevice(device, format, width, height, config)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	dev.lock = lock
+
+	return dev, nil
 }
 
-func (dev *Device) GetFrame() (*image.RGBA, error) {
+func openDevice(device string, format Format, width, height int, config config) (*Device, error) {
 
-	imageSize := (dev.width * dev.height) * (4 / 2)
-	frame := make([]byte, imageSize)
+	sys := config.backend
 
-	qbuf := v4l2_buffer{
-		Type:    V4L2_BUF_TYPE_VIDEO_CAPTURE,
-		Memory:  V4L2_MEMORY_USERPTR,
-		Userptr: uint64(toUintptr(frame)),
-		Length:  uint32(len(frame)),
+	fd, err := sys.Open(device, os.O_RDWR|syscall.O_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open device: %w", busyError(err))
 	}
 
-	bqbuf := toBytes(qbuf)
+	h := handle{device: device, fd: fd, sys: sys, logger: config.logger}
+
+	caps, err := h.queryCap()
+	if err != nil {
+		sys.Close(fd)
+		return nil, fmt.Errorf("Failed to query capabilities: %v", err.Error())
+	}
+
+	h.caps = caps
+
+	if !h.capturesType(config.bufType) {
+		sys.Close(fd)
+		return nil, ErrNotCaptureDevice
+	}
 
-	if err := ioctl(dev.fd, VIDIOC_QBUF, toUintptr(bqbuf)); err != nil {
-		return nil, fmt.Errorf("Failed to qbuf: %v", err.Error())
+	if !h.hasCap(V4L2_CAP_STREAMING) && h.hasCap(V4L2_CAP_READWRITE) {
+		if _, ok := sys.(ReadBackend); !ok {
+			sys.Close(fd)
+			return nil, errors.New("Device only supports read() I/O, which the backend does not")
+		}
+		h.readIO = true
 	}
 
-	if err := ioctl(dev.fd, VIDIOC_DQBUF, toUintptr(bqbuf)); err != nil {
-		return nil, fmt.Errorf("Failed to dqbuf: %v", err.Error())
+	dev := &Device{
+		handle:  h,
+		format:  format,
+		scale:   1,
+		config:  config,
+		bufType: config.bufType,
 	}
 
-	r := image.Rect(0, 0, dev.width, dev.height)
-	im := image.NewRGBA(r)
+	if dev.bufType == V4L2_BUF_TYPE_VIDEO_CAPTURE {
 
-	frameToImage(frame, im)
+		if len(config.formats) > 0 {
+			f, err := dev.chooseFormat(config.formats)
+			if err != nil {
+				sys.Close(fd)
+				return nil, err
+			}
+			format, dev.format = f, f
+		}
 
-	return im, nil
+		w, h := width, height
+		if config.minWidth > 0 || config.minHeight > 0 {
+			w, h = dev.pickSize(format, width, height, config.minWidth, config.minHeight)
+		}
+
+		pix, err := dev.setFormat(format, w, h, config)
+		if err != nil {
+			sys.Close(fd)
+			return nil, fmt.Errorf("Failed to set format: %w", busyError(err))
+		}
+
+		if int(pix.Width) < config.minWidth || int(pix.Height) < config.minHeight {
+			sys.Close(fd)
+			return nil, fmt.Errorf("Driver offers %dx%d, less than the minimum %dx%d",
+				pix.Width, pix.Height, config.minWidth, config.minHeight)
+		}
+
+		dev.adjustment = formatAdjustment(format, width, height, config, pix)
+		format, dev.format = Format(pix.Pixelformat), Format(pix.Pixelformat)
+		dev.pix = pix
+		dev.width = int(pix.Width)
+		dev.height = int(pix.Height)
+
+	} else {
+
+		size, err := dev.setRawFormat(dev.bufType, format)
+		if err != nil {
+			sys.Close(fd)
+			return nil, fmt.Errorf("Failed to set format: %w", busyError(err))
+		}
+
+		dev.rawSize = size
+	}
+
+	if err := dev.setUserptr(dev.bufType, config); err != nil {
+		sys.Close(fd)
+		return nil, fmt.Errorf("Failed to set user space ptr: %w", busyError(err))
+	}
+
+	dev.prepareSpare()
+
+	if dev.bufType == V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		dev.events = dev.subscribeSourceChange()
+	}
+
+	if err := dev.WarmUp(config.warmup); err != nil {
+		sys.Close(fd)
+		return nil, fmt.Errorf("Failed to warm up: %w", err)
+	}
+
+	if config.hardwareJPEG && format == V4L2_PIX_FMT_MJPEG {
+		dev.jpeg = findJPEGDecoder(dev.width, dev.height, config)
+	}
+
+	return dev, nil
 }
 
-func frameToImage(frame []byte, im *image.RGBA) {
+// busyError maps EBUSY, which the driver returns while another file handle
+// owns the stream, to ErrDeviceBusy.
+func busyError(err error) error {
 
-	p := 0
-	for i := 0; i < len(frame); i += 4 {
+	if errors.Is(err, syscall.EBUSY) {
+		return ErrDeviceBusy
+	}
 
-		im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
-			frame[i+0],
-			frame[i+1],
-			frame[i+3])
-		p += 4
+	return err
+}
 
-		im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
-			frame[i+2],
-			frame[i+1],
-			frame[i+3])
-		p += 4
+// OpenIndex opens /dev/video<n>.
+func OpenIndex(n int, width, height int, opts ...Option) (*Device, error) {
+	return Open(fmt.Sprintf("/dev/video%d", n), width, height, opts...)
+}
+
+func (dev *Device) Close() {
+
+	dev.DisableBackgroundCapture()
+	dev.stopWatches()
 
+	dev.mu.Lock()
+	l := dev.latest
+	dev.mu.Unlock()
+
+	if l != nil {
+		l.close(dev)
 	}
 
+	dev.closeNode()
+	dev.freeBounce()
+
+	if dev.jpeg != nil {
+		dev.jpeg.close()
+		dev.jpeg = nil
+	}
 }
 
-func setFormat(fd int, format uint32, width, height int) error {
+// closeNode closes the device node and releases its lock, leaving the
+// Device to be opened again by reopen.
+func (dev *Device) closeNode() {
 
-	f := v4l2_pix_format{
-		Type:        uint32(V4L2_BUF_TYPE_VIDEO_CAPTURE),
-		Width:       uint32(width),
-		Height:      uint32(height),
-		Pixelformat: uint32(format),
+	if dev.fd >= 0 {
+		dev.sys.Close(dev.fd)
+		dev.fd = -1
 	}
 
-	b := toBytes(f)
+	dev.buffers = 0
+	dev.streaming = false
+
+	if dev.lock != nil {
+		dev.lock.Close()
+		dev.lock = nil
+	}
+}
+
+// reopen opens the device node again after closeNode, negotiating the
+// format and buffers it last had. Settings made on the Device itself, such
+// as its scale and transform, are kept.
+func (dev *Device) reopen() error {
 
-	if err := ioctl(fd, VIDIOC_S_FMT, toUintptr(b)); err != nil {
+	fresh, err := openConfig(dev.device, dev.format, dev.width, dev.height, dev.config)
+	if err != nil {
 		return err
 	}
 
+	fresh.timeout = dev.timeout
+	fresh.logger = dev.logger
+
+	dev.handle = fresh.handle
+	dev.pix = fresh.pix
+	dev.width = fresh.width
+	dev.height = fresh.height
+	dev.events = fresh.events
+	dev.lock = fresh.lock
+	dev.rawSize = fresh.rawSize
+
+	dev.prepared = 0
+	dev.prepareSpare()
+
+	if dev.scale > dev.width || dev.scale > dev.height {
+		dev.scale = 1
+	}
+
 	return nil
+}
+
+// SetParallel splits frame conversion into horizontal bands converted
+// concurrently, one per GOMAXPROCS. This only pays off at large resolutions.
+func (dev *Device) SetParallel(parallel bool) {
+	dev.parallel = parallel
+}
 
+// SetStrict makes captures fail with ErrFrameCorrupt rather than return a
+// frame the driver flagged with V4L2_BUF_FLAG_ERROR. Otherwise such frames
+// are returned as they are, with the flag reported by FrameInfo.Error.
+func (dev *Device) SetStrict(strict bool) {
+	dev.strict = strict
 }
 
-func setUserptr(fd int) error {
+// BytesPerLine is the stride of a captured line as negotiated with the
+// driver, including any padding it adds.
+func (dev *Device) BytesPerLine() int {
+	return int(dev.pix.Bytesperline)
+}
 
-	r := v4l2_requestbuffers{
-		Count:  1,
-		Type:   V4L2_BUF_TYPE_VIDEO_CAPTURE,
-		Memory: V4L2_MEMORY_USERPTR,
+// ImageSize is the buffer size the driver requires for one frame.
+func (dev *Device) ImageSize() int {
+	return int(dev.pix.Sizeimage)
+}
+
+// String describes the device for logs, such as
+// "v4l.Device{path:/dev/video0, 1280x720, YUYV, 1 buffer, streaming}".
+func (dev *Device) String() string {
+
+	desc := fmt.Sprintf("%dx%d, %v", dev.width, dev.height, dev.format)
+	if dev.bufType != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		desc = fmt.Sprintf("buffer type %d", dev.bufType)
 	}
 
-	b := toBytes(r)
+	buffers := fmt.Sprintf("%d buffers", dev.buffers)
+	if dev.buffers == 1 {
+		buffers = "1 buffer"
+	}
+
+	state := "stopped"
+	if dev.fd < 0 {
+		state = "closed"
+	} else if dev.readIO {
+		state = "read I/O"
+	} else if dev.streaming {
+		state = "streaming"
+	}
+
+	return fmt.Sprintf("v4l.Device{path:%s, %s, %s, %s}", dev.device, desc, buffers, state)
+}
+
+// Buffers is the number of buffers the driver granted for the stream,
+// which may be fewer than requested on devices short of memory. It is
+// zero while the device is not streaming.
+func (dev *handle) Buffers() int {
+	return dev.buffers
+}
+
+// Name returns the path the device was opened with.
+func (dev *handle) Name() string {
+	return dev.device
+}
+
+// SetScale makes GetFrame downscale by an integer factor n during conversion
+// by sampling every nth pixel. A scale of 1 disables downscaling.
+func (dev *Device) SetScale(n int) error {
+
+	if n < 1 || n > dev.width || n > dev.height {
+		return fmt.Errorf("Invalid scale: %d", n)
+	}
+
+	dev.scale = n
+
+	return nil
+}
+
+func (dev *Device) GetFrame() (*image.RGBA, error) {
+
+	im, _, err := dev.getFrame()
+
+	return im, err
+}
+
+// getFrame is GetFrame also returning the info of the frame captured.
+func (dev *Device) getFrame() (*image.RGBA, FrameInfo, error) {
+
+	dev.mu.Lock()
+	bg := dev.background
+	dev.mu.Unlock()
 
-	if err := ioctl(fd, VIDIOC_REQBUFS, toUintptr(b)); err != nil {
+	if bg != nil {
+		im, info, err := bg.current()
+		if err != nil {
+			return nil, info, err
+		}
+		return dev.transformed(im, info), info, nil
+	}
+
+	if err := dev.checkConvert(); err != nil {
+		return nil, FrameInfo{}, err
+	}
+
+	frame, info, err := dev.readFrame()
+	if err != nil {
+		return nil, info, err
+	}
+
+	// When the driver already delivers image.RGBA's layout the capture
+	// buffer becomes the image's Pix without a copy. The image then shares
+	// the buffer's lifetime, which is safe only as long as the buffer is
+	// never handed back to the driver for another frame.
+	if dev.zeroCopy() {
+		r := dev.bounds()
+		im := &image.RGBA{Pix: frame, Stride: dev.stride(), Rect: r}
+		setOpaque(im)
+		return dev.transformed(im, info), info, nil
+	}
+
+	im := image.NewRGBA(dev.bounds())
+
+	dev.convert(frame, im)
+
+	return dev.transformed(im, info), info, nil
+}
+
+// GetFrameNRGBA captures a frame like GetFrame but returns it as
+// *image.NRGBA, for libraries that switch on the image type. The converters
+// leave every pixel opaque, where premultiplied and non-premultiplied
+// values are the same, so the pixels are shared rather than converted.
+func (dev *Device) GetFrameNRGBA() (*image.NRGBA, error) {
+
+	im, err := dev.GetFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	return &image.NRGBA{Pix: im.Pix, Stride: im.Stride, Rect: im.Rect}, nil
+}
+
+// GetFrameBytes captures a frame and writes its RGBA pixels into dst,
+// returning the number of bytes written and the image dimensions.
+func (dev *Device) GetFrameBytes(dst []byte) (int, int, int, error) {
+
+	r := transformBounds(dev.bounds(), dev.transform)
+	size := r.Dx() * r.Dy() * 4
+
+	if len(dst) < size {
+		return 0, 0, 0, fmt.Errorf("Buffer too small: %d < %d", len(dst), size)
+	}
+
+	if err := dev.checkConvert(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	frame, info, err := dev.readFrame()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	// The source may have switched resolution during the capture.
+	r = transformBounds(dev.bounds(), dev.transform)
+	size = r.Dx() * r.Dy() * 4
+
+	if len(dst) < size {
+		return 0, 0, 0, fmt.Errorf("Buffer too small: %d < %d", len(dst), size)
+	}
+
+	im := &image.RGBA{Pix: dst[:size], Stride: r.Dx() * 4, Rect: r}
+
+	if dev.transform == Identity {
+		dev.convert(frame, im)
+	} else {
+		src := image.NewRGBA(dev.bounds())
+		dev.convert(frame, src)
+		transformImage(src, dev.transform, im)
+	}
+
+	dev.drawOverlay(im, info)
+
+	return size, r.Dx(), r.Dy(), nil
+}
+
+// GetFrameInto captures a frame and converts it into dst, which must have
+// exactly the bounds GetFrame's images have and lines packed 4 bytes per
+// pixel. The capture buffer is reused across calls, so with no transform
+// set nothing is allocated, apart from the copy the packed 4:2:2, RGBA32,
+// Y210 and Y16 converters make of frames whose lines the driver pads and
+// the decoding of compressed frames; callers can alternate between images
+// of their own for as long as they like.
+func (dev *Device) GetFrameInto(dst *image.RGBA) error {
+
+	if err := dev.checkConvert(); err != nil {
 		return err
 	}
 
-	b2 := toBytes(V4L2_BUF_TYPE_VIDEO_CAPTURE)
+	for {
+
+		buf := dev.spareBuffer()
+
+		info, err := dev.readFrameInto(buf)
+		if err == errSourceChanged {
+			continue
+		}
+		if err == nil {
+			err = dev.convertInto(buf, info, dst)
+		}
+
+		dev.putBuffer(buf)
 
-	if err := ioctl(fd, VIDIOC_STREAMON, toUintptr(b2)); err != nil {
 		return err
 	}
+}
+
+// convertInto converts frame, described by info, into dst after checking
+// that dst matches the capture, applying the transform and overlay.
+func (dev *Device) convertInto(frame []byte, info FrameInfo, dst *image.RGBA) error {
+
+	r := transformBounds(dev.bounds(), dev.transform)
+
+	if dst.Rect != r {
+		return fmt.Errorf("Image bounds %v do not match the capture %v", dst.Rect, r)
+	}
+
+	if dst.Stride != r.Dx()*4 || len(dst.Pix) < r.Dy()*dst.Stride {
+		return fmt.Errorf("Image stride %d does not match the capture %d", dst.Stride, r.Dx()*4)
+	}
+
+	if dev.transform == Identity {
+		dev.convert(frame, dst)
+	} else {
+		src := image.NewRGBA(dev.bounds())
+		dev.convert(frame, src)
+		transformImage(src, dev.transform, dst)
+	}
+
+	dev.drawOverlay(dst, info)
 
 	return nil
 }
 
-func toBytes(i interface{}) []byte {
-	buf := &bytes.Buffer{}
-	binary.Write(buf, binary.LittleEndian, i)
-	return buf.Bytes()
+// GetRawFrame captures a frame and returns the bytes the driver filled in,
+// without any conversion. This is the only way to get at the compressed
+// data of formats such as V4L2_PIX_FMT_MJPEG and V4L2_PIX_FMT_H264.
+func (dev *Device) GetRawFrame() ([]byte, FrameInfo, error) {
+
+	frame, info, err := dev.readFrame()
+	if err != nil {
+		return nil, info, err
+	}
+
+	if int(info.Bytesused) < len(frame) {
+		frame = frame[:info.Bytesused]
+	}
+
+	return frame, info, nil
 }
 
-func toUintptr(b []byte) uintptr {
-	return (*reflect.SliceHeader)(unsafe.Pointer(&b)).Data
+func (dev *Device) readFrame() ([]byte, FrameInfo, error) {
+
+	for {
+
+		frame := frameBuffer(dev.frameSize())
+
+		info, err := dev.readFrameInto(frame)
+		if err == errSourceChanged {
+			continue
+		}
+		if err != nil {
+			return nil, info, err
+		}
+
+		return frame, info, nil
+	}
 }
 
-func ioctl(fd int, req, arg uintptr) error {
-	_, _, e := syscall.RawSyscall(syscall.SYS_IOCTL, uintptr(fd), req, arg)
-	if e != 0 {
-		log.Printf("IOCTL[%d::%x]: %d -> %v\n", fd, req, e, e)
-		return os.NewSyscallError("ioctl", e)
+// readFrameInto queues frame to the driver and waits for it to be filled.
+// When the source has changed resolution it renegotiates the format and
+// returns errSourceChanged; frame is then sized for the old format and the
+// caller must capture again into a new buffer. Fields of an alternating
+// stream are woven into frame; see Weaving.
+func (dev *Device) readFrameInto(frame []byte) (FrameInfo, error) {
+
+	if dev.Weaving() {
+		return dev.weaveInto(frame, dev.readBuffer)
 	}
-	return nil
+
+	return dev.readBuffer(frame)
+}
+
+// readBuffer is readFrameInto for a single buffer of the driver's.
+func (dev *Device) readBuffer(frame []byte) (FrameInfo, error) {
+
+	bqbuf, err := dev.queueFrame(frame)
+	if err != nil {
+		return FrameInfo{}, err
+	}
+
+	return dev.dequeueFrame(frame, bqbuf)
+}
+
+// queueFrame hands frame to the driver to be filled and returns the buffer
+// description to dequeue it with.
+func (dev *Device) queueFrame(frame []byte) ([]byte, error) {
+
+	if dev.readIO {
+		return nil, nil
+	}
+
+	buf := frame
+	if !pageAligned(frame) {
+		b, err := dev.bounceBuffer(len(frame))
+		if err != nil {
+			return nil, err
+		}
+		buf = b
+	}
+
+	qbuf := v4l2_buffer{
+		Type:    dev.bufType,
+		Memory:  V4L2_MEMORY_USERPTR,
+		Userptr: uint64(toUintptr(buf)),
+		Length:  uint32(len(buf)),
+	}
+
+	bqbuf := toBytes(qbuf)
+
+	if err := dev.ioctl(VIDIOC_QBUF, toUintptr(bqbuf)); err != nil {
+		return nil, fmt.Errorf("Failed to qbuf: %w", err)
+	}
+
+	if dev.timing {
+		dev.stats.queue()
+	}
+
+	return bqbuf, nil
+}
+
+// prepareSpare issues PREPARE_BUF, with WithPrepareBuffers, for the spare
+// buffer the captures that reuse memory queue, once streaming has started.
+// A driver that does not implement it is not asked again; QBUF prepares
+// the buffer itself then.
+func (dev *Device) prepareSpare() {
+
+	if !dev.config.prepareBufs || dev.noPrepare || dev.readIO || dev.bufType != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		return
+	}
+
+	buf := dev.spareBuffer()
+	defer dev.putBuffer(buf)
+
+	qbuf := v4l2_buffer{
+		Type:    dev.bufType,
+		Memory:  V4L2_MEMORY_USERPTR,
+		Userptr: uint64(toUintptr(buf)),
+		Length:  uint32(len(buf)),
+	}
+
+	err := dev.ioctl(VIDIOC_PREPARE_BUF, toUintptr(toBytes(qbuf)))
+	if errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EINVAL) {
+		dev.logf("PREPARE_BUF unsupported, queuing buffers unprepared")
+		dev.noPrepare = true
+		return
+	}
+
+	if err == nil {
+		dev.prepared = qbuf.Userptr
+	}
+}
+
+// dequeueFrame waits for the buffer queueFrame queued to be filled, as
+// readFrameInto describes.
+func (dev *Device) dequeueFrame(frame, bqbuf []byte) (FrameInfo, error) {
+
+	if dev.readIO {
+		return dev.readInto(frame)
+	}
+
+	if err := dev.ioctl(VIDIOC_DQBUF, toUintptr(bqbuf)); err != nil {
+		// The buffer is still queued, so the driver may write into it once
+		// the caller has let it go; STREAMOFF takes it back first.
+		dev.restartStream()
+		return FrameInfo{}, fmt.Errorf("Failed to dqbuf: %w", err)
+	}
+
+	if dev.timing {
+		dev.stats.recordWait()
+	}
+
+	var qbuf v4l2_buffer
+	if err := fromBytes(bqbuf, &qbuf); err != nil {
+		return FrameInfo{}, fmt.Errorf("Failed to read dqbuf: %v", err.Error())
+	}
+
+	if qbuf.Userptr != uint64(toUintptr(frame)) {
+		copy(frame, dev.bounce)
+	}
+
+	info := frameInfo(qbuf)
+
+	sized := dev.sizeChanged(info)
+
+	if (dev.events && dev.sourceChanged()) || sized {
+		changed, err := dev.renegotiate()
+		if err != nil {
+			return info, err
+		}
+		if changed {
+			return info, errSourceChanged
+		}
+		if sized {
+			dev.shortSize, dev.shortPix = info.Bytesused, dev.pix
+		}
+	}
+
+	return dev.finishFrame(frame, info)
+}
+
+// readInto reads a frame from a device without streaming I/O. The driver
+// reports nothing about the frame, so it is numbered as it arrives and
+// timestamped on CLOCK_MONOTONIC like the frames of streaming drivers.
+func (dev *Device) readInto(frame []byte) (FrameInfo, error) {
+
+	if dev.timeout > 0 {
+		if _, err := dev.sys.Poll(dev.fd, POLLIN, dev.timeout); err != nil {
+			return FrameInfo{}, err
+		}
+	}
+
+	start := time.Now()
+
+	n, err := dev.sys.(ReadBackend).Read(dev.fd, frame)
+	if err != nil {
+		return FrameInfo{}, fmt.Errorf("Failed to read frame: %w", err)
+	}
+
+	if dev.timing {
+		dev.stats.recordRead(start)
+	}
+
+	info := FrameInfo{
+		Sequence:  dev.readSequence,
+		Field:     dev.pix.Field,
+		Flags:     V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC,
+		Bytesused: uint32(n),
+		Timestamp: monotonicNow(),
+	}
+
+	dev.readSequence++
+
+	return dev.finishFrame(frame, info)
+}
+
+// finishFrame accounts for a captured frame and runs what watches every
+// frame.
+func (dev *Device) finishFrame(frame []byte, info FrameInfo) (FrameInfo, error) {
+
+	info.Width, info.Height = dev.width, dev.height
+
+	if dev.format == V4L2_PIX_FMT_MJPEG && !jpegComplete(frame, info) {
+		info.Flags |= V4L2_BUF_FLAG_ERROR
+	}
+
+	dev.stats.record(info)
+
+	if dev.strict && info.Error() {
+		return info, ErrFrameCorrupt
+	}
+
+	dev.mu.Lock()
+	ae := dev.ae
+	dev.mu.Unlock()
+
+	if ae != nil {
+		ae.update(dev, frame)
+	}
+
+	return info, nil
+}
+
+// CaptureBurst captures n frames back to back into dst, which must hold n
+// times FrameSize bytes, frame i starting at i*FrameSize. Nothing is
+// allocated per frame, so bursts are free of GC pauses. Frames are left
+// raw, as GetRawFrame returns them. Drivers are handed page aligned memory
+// only, so frames that do not start on a page boundary are captured into
+// an aligned buffer and copied into dst; a dst allocated page aligned with
+// a FrameSize that is a multiple of the page size avoids the copy.
+func (dev *Device) CaptureBurst(n int, dst []byte) ([]FrameInfo, error) {
+
+	size := dev.frameSize()
+
+	if n < 1 {
+		return nil, fmt.Errorf("Invalid frame count: %d", n)
+	}
+
+	if len(dst) < n*size {
+		return nil, fmt.Errorf("Buffer too small: %d < %d", len(dst), n*size)
+	}
+
+	infos := make([]FrameInfo, n)
+
+	for i := range infos {
+
+		info, err := dev.readFrameInto(dst[i*size : (i+1)*size])
+		if err == errSourceChanged {
+			return infos[:i], fmt.Errorf("Format changed after %d frames", i)
+		}
+		if err != nil {
+			return infos[:i], err
+		}
+
+		infos[i] = info
+	}
+
+	return infos, nil
+}
+
+// WarmUp captures and discards n frames, as many cameras deliver a few
+// dark or garbled ones right after streaming starts while auto-exposure
+// converges. Frames the driver flags as corrupt count toward n.
+func (dev *Device) WarmUp(n int) error {
+
+	var frame []byte
+
+	for i := 0; i < n; i++ {
+
+		if len(frame) != dev.frameSize() {
+			frame = frameBuffer(dev.frameSize())
+		}
+
+		_, err := dev.readFrameInto(frame)
+		if err == errSourceChanged {
+			i--
+			continue
+		}
+		if err != nil && err != ErrFrameCorrupt {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FrameSize is the number of bytes one raw frame occupies in a
+// CaptureBurst buffer.
+func (dev *Device) FrameSize() int {
+	return dev.frameSize()
+}
+
+// GetFrameRGBA64 captures a frame keeping the full bit depth of 10-bit
+// formats such as V4L2_PIX_FMT_Y210.
+func (dev *Device) GetFrameRGBA64() (*image.RGBA64, error) {
+
+	if dev.format != V4L2_PIX_FMT_Y210 {
+		return nil, fmt.Errorf("Unsupported format for RGBA64: %v", dev.format)
+	}
+
+	frame, _, err := dev.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	im := image.NewRGBA64(dev.bounds())
+
+	y210ToImage64(unpadFrame(frame, dev.width*4, dev.stride(), dev.height), dev.width, dev.scale, im)
+
+	return im, nil
+}
+
+// frameSize is the buffer size needed for one frame: the Sizeimage the
+// driver negotiated, or the size the layout implies when the driver
+// reports less.
+func (dev *Device) frameSize() int {
+
+	if dev.bufType != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		return dev.rawSize
+	}
+
+	size := dev.layoutSize()
+	if n := dev.ImageSize(); n > size {
+		return n
+	}
+
+	return size
+}
+
+// layoutSize is the size of a frame as the format lays it out, or zero for
+// formats the package does not know.
+func (dev *Device) layoutSize() int {
+	return ImageSizeFor(dev.format, dev.width, dev.height, dev.BytesPerLine())
+}
+
+// ImageSizeFor returns how many bytes a frame of format occupies at width x
+// height with lines bytesperline apart, or packed when bytesperline is
+// smaller than a line. Compressed frames have no fixed size, so they are
+// given as much room as packed 4:2:2. It returns zero for formats the
+// package does not know, for which the driver's Sizeimage is the only
+// guide.
+func ImageSizeFor(format Format, width, height, bytesperline int) int {
+
+	if format.Compressed() {
+		return width * height * 2
+	}
+
+	line := packedLine(format, width)
+	if line == 0 {
+		return 0
+	}
+
+	if bytesperline > line {
+		line = bytesperline
+	}
+
+	// The chroma plane of the semi-planar formats follows the luma plane
+	// and shares its stride.
+	if semiPlanar(format) {
+		return line * (height + (height+1)/2)
+	}
+
+	return line * height
+}
+
+// packedLine is the size of a line of format without padding, or zero for
+// formats the package does not know.
+func packedLine(format Format, width int) int {
+
+	if _, ok := bayer10Pattern(format); ok {
+		return width * 5 / 4
+	}
+
+	switch format {
+	case V4L2_PIX_FMT_RGB24, V4L2_PIX_FMT_BGR24:
+		return width * 3
+	case V4L2_PIX_FMT_RGB32:
+		return width * 4
+	}
+
+	return width * bytesPerPixel(format)
+}
+
+// stride is the distance between lines in the first plane: the packed
+// line size, or the driver's Bytesperline when it pads lines beyond that.
+// The chroma plane of the semi-planar formats shares it.
+func (dev *Device) stride() int {
+
+	line := packedLine(dev.format, dev.width)
+
+	if bpl := dev.BytesPerLine(); bpl > line {
+		return bpl
+	}
+
+	return line
+}
+
+// bandable formats hold each line of pixels independently of the others,
+// so a frame can be converted a band of lines at a time.
+func bandable(format Format) bool {
+
+	if _, ok := packed422Layouts[format]; ok {
+		return true
+	}
+
+	return format == V4L2_PIX_FMT_RGBA32 || format == V4L2_PIX_FMT_Y210 || format == V4L2_PIX_FMT_Y16
+}
+
+// bytesPerPixel is the size of a pixel in the first plane of format, or
+// zero if the format cannot be converted.
+func bytesPerPixel(format Format) int {
+
+	switch format {
+	case V4L2_PIX_FMT_NV12, V4L2_PIX_FMT_NV21:
+		return 1
+	case V4L2_PIX_FMT_YUYV, V4L2_PIX_FMT_UYVY, V4L2_PIX_FMT_YVYU, V4L2_PIX_FMT_VYUY, V4L2_PIX_FMT_Y16:
+		return 2
+	case V4L2_PIX_FMT_RGBA32, V4L2_PIX_FMT_Y210:
+		return 4
+	}
+
+	return 0
+}
+
+func (dev *Device) bounds() image.Rectangle {
+	return image.Rect(0, 0, dev.width/dev.scale, dev.height/dev.scale)
+}
+
+func (dev *Device) checkConvert() error {
+
+	if dev.bufType != V4L2_BUF_TYPE_VIDEO_CAPTURE {
+		return fmt.Errorf("Buffer type %d holds no images", dev.bufType)
+	}
+
+	if lookupConverter(dev.format) == nil {
+		return fmt.Errorf("No converter for format: %v", dev.format)
+	}
+
+	return nil
+}
+
+// zeroCopy reports whether a captured frame is already the image GetFrame
+// returns, so that it can be used without converting.
+func (dev *Device) zeroCopy() bool {
+	return dev.format == V4L2_PIX_FMT_RGBA32 && dev.scale == 1 && dev.lut == nil
+}
+
+func (dev *Device) convert(frame []byte, im *image.RGBA) {
+
+	if dev.timing {
+		defer dev.stats.recordConvert(time.Now())
+	}
+
+	c := lookupConverter(dev.format)
+	stride := dev.stride()
+
+	if dev.jpeg != nil && dev.format == V4L2_PIX_FMT_MJPEG {
+		c = dev.jpeg.converter(c)
+	}
+
+	if lut := dev.lut; lut != nil {
+		if fused := lookupLUTConverter(dev.format, lut); fused != nil {
+			c = fused
+		} else {
+			convert := c
+			c = func(src []byte, width, height, stride, scale int, dst *image.RGBA) {
+				convert(src, width, height, stride, scale, dst)
+				applyLUT(dst, lut)
+			}
+		}
+	}
+
+	if !dev.parallel || !bandable(dev.format) {
+		c(frame, dev.width, dev.height, stride, dev.scale, im)
+		return
+	}
+
+	b := im.Bounds()
+	bandStride := stride * dev.scale
+
+	n := runtime.GOMAXPROCS(0)
+	if n > b.Dy() {
+		n = b.Dy()
+	}
+	band := (b.Dy() + n - 1) / n
+
+	var wg sync.WaitGroup
+	for y := 0; y < b.Dy(); y += band {
+
+		y1 := y + band
+		if y1 > b.Dy() {
+			y1 = b.Dy()
+		}
+
+		end := y1 * bandStride
+		if end > len(frame) {
+			end = len(frame)
+		}
+
+		sub := im.SubImage(image.Rect(b.Min.X, b.Min.Y+y, b.Max.X, b.Min.Y+y1)).(*image.RGBA)
+		src := frame[y*bandStride : end]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c(src, dev.width, len(src)/stride, stride, dev.scale, sub)
+		}()
+	}
+	wg.Wait()
+}
+
+// packed422 gives the position of each sample within the four bytes that
+// hold two pixels of a packed 4:2:2 format.
+type packed422 struct {
+	y0, cb, y1, cr int
+}
+
+var packed422Layouts = map[Format]packed422{
+	V4L2_PIX_FMT_YUYV: {y0: 0, cb: 1, y1: 2, cr: 3},
+	V4L2_PIX_FMT_UYVY: {y0: 1, cb: 0, y1: 3, cr: 2},
+	V4L2_PIX_FMT_YVYU: {y0: 0, cb: 3, y1: 2, cr: 1},
+	V4L2_PIX_FMT_VYUY: {y0: 1, cb: 2, y1: 3, cr: 0},
+}
+
+// frameToImage converts a packed 4:2:2 frame, mapping the RGB samples
+// through lut as they are written.
+func frameToImage(frame []byte, l packed422, lut *[256]uint8, im *image.RGBA) {
+
+	p := 0
+	for i := 0; i+4 <= len(frame) && p+8 <= len(im.Pix); i += 4 {
+
+		r, g, b := color.YCbCrToRGB(
+			frame[i+l.y0],
+			frame[i+l.cb],
+			frame[i+l.cr])
+		im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = lut[r], lut[g], lut[b], 0xff
+		p += 4
+
+		r, g, b = color.YCbCrToRGB(
+			frame[i+l.y1],
+			frame[i+l.cb],
+			frame[i+l.cr])
+		im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = lut[r], lut[g], lut[b], 0xff
+		p += 4
+
+	}
+
+}
+
+func frameToScaledImage(frame []byte, width, scale int, l packed422, lut *[256]uint8, im *image.RGBA) {
+
+	stride := width * 2
+	b := im.Bounds()
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+
+		row := frame[y*scale*stride:]
+
+		for x := 0; x < b.Dx(); x++ {
+
+			sx := x * scale
+			i := (sx / 2) * 4
+
+			y := l.y0
+			if sx%2 == 1 {
+				y = l.y1
+			}
+
+			r, g, b := color.YCbCrToRGB(
+				row[i+y],
+				row[i+l.cb],
+				row[i+l.cr])
+			im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = lut[r], lut[g], lut[b], 0xff
+			p += 4
+		}
+	}
+
+}
+
+func rgbaToImage(frame []byte, width, scale int, im *image.RGBA) {
+
+	b := im.Bounds()
+	stride := width * 4
+
+	if scale == 1 {
+		copy(im.Pix[:b.Dx()*b.Dy()*4], frame)
+		setOpaque(im)
+		return
+	}
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+
+		row := frame[y*scale*stride:]
+
+		for x := 0; x < b.Dx(); x++ {
+			copy(im.Pix[p:p+3], row[x*scale*4:])
+			im.Pix[p+3] = 0xff
+			p += 4
+		}
+	}
+
+}
+
+// setOpaque sets the alpha of every pixel of im to 0xff. Drivers fill the
+// alpha byte of RGBA32 with whatever the hardware leaves there, often zero,
+// while a camera image has no transparency.
+func setOpaque(im *image.RGBA) {
+
+	b := im.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := im.Pix[im.PixOffset(b.Min.X, y):]
+		for i := 3; i < b.Dx()*4; i += 4 {
+			row[i] = 0xff
+		}
+	}
+}
+
+func semiPlanar(format Format) bool {
+	return format == V4L2_PIX_FMT_NV12 || format == V4L2_PIX_FMT_NV21
+}
+
+// nv12Sample reads pixel x, y of a 4:2:0 frame made of a full resolution
+// luma plane followed by a half resolution plane of interleaved chroma
+// pairs, Cb first for NV12 and Cr first for NV21. Both planes have lines
+// stride bytes apart.
+func nv12Sample(frame []byte, stride, height, x, y int, swapped bool) (uint8, uint8, uint8) {
+
+	c := stride*height + (y/2)*stride + (x/2)*2
+
+	cb, cr := frame[c], frame[c+1]
+	if swapped {
+		cb, cr = cr, cb
+	}
+
+	return frame[y*stride+x], cb, cr
+}
+
+func nv12ToImage(frame []byte, stride, height, scale int, swapped bool, lut *[256]uint8, im *image.RGBA) {
+
+	bounds := im.Bounds()
+
+	p := 0
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+
+			r, g, b := color.YCbCrToRGB(
+				nv12Sample(frame, stride, height, x*scale, y*scale, swapped))
+			im.Pix[p+0], im.Pix[p+1], im.Pix[p+2], im.Pix[p+3] = lut[r], lut[g], lut[b], 0xff
+			p += 4
+		}
+	}
+
+}
+
+// Y210 is packed 4:2:2 like YUYV but with each sample stored as a little
+// endian 16-bit word holding 10 significant bits in its high bits.
+func y210Sample(frame []byte, width, x, y int) (uint16, uint16, uint16) {
+
+	i := (y*width + x&^1) * 4
+
+	ys := binary.LittleEndian.Uint16(frame[i+(x&1)*4:]) >> 6
+	cb := binary.LittleEndian.Uint16(frame[i+2:]) >> 6
+	cr := binary.LittleEndian.Uint16(frame[i+6:]) >> 6
+
+	return ys, cb, cr
+}
+
+func y210ToImage(frame []byte, width, scale int, im *image.RGBA) {
+
+	b := im.Bounds()
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+
+			ys, cb, cr := y210Sample(frame, width, x*scale, y*scale)
+
+			im.Pix[p+0], im.Pix[p+1], im.Pix[p+2] = color.YCbCrToRGB(
+				uint8(ys>>2),
+				uint8(cb>>2),
+				uint8(cr>>2))
+			im.Pix[p+3] = 0xff
+			p += 4
+		}
+	}
+
+}
+
+func y210ToImage64(frame []byte, width, scale int, im *image.RGBA64) {
+
+	b := im.Bounds()
+
+	p := 0
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+
+			ys, cb, cr := y210Sample(frame, width, x*scale, y*scale)
+			r, g, bl := yCbCrToRGB10(ys, cb, cr)
+
+			binary.BigEndian.PutUint16(im.Pix[p+0:], r)
+			binary.BigEndian.PutUint16(im.Pix[p+2:], g)
+			binary.BigEndian.PutUint16(im.Pix[p+4:], bl)
+			binary.BigEndian.PutUint16(im.Pix[p+6:], 0xffff)
+			p += 8
+		}
+	}
+
+}
+
+// yCbCrToRGB10 is the JFIF conversion used by color.YCbCrToRGB carried out
+// on 10-bit samples, returning 16-bit channels.
+func yCbCrToRGB10(y, cb, cr uint16) (uint16, uint16, uint16) {
+
+	yy := int32(y) << 16
+	cb1 := int32(cb) - 512
+	cr1 := int32(cr) - 512
+
+	r := (yy + 91881*cr1) >> 16
+	g := (yy - 22554*cb1 - 46802*cr1) >> 16
+	b := (yy + 116130*cb1) >> 16
+
+	return expand10(r), expand10(g), expand10(b)
+}
+
+func expand10(v int32) uint16 {
+
+	if v < 0 {
+		v = 0
+	} else if v > 1023 {
+		v = 1023
+	}
+
+	return uint16(v<<6 | v>>4)
+}
+
+func (dev *handle) setFormat(format Format, width, height int, config config) (v4l2_pix_format, error) {
+
+	f := v4l2_format{
+		Type: uint32(V4L2_BUF_TYPE_VIDEO_CAPTURE),
+		Pix: v4l2_pix_format{
+			Width:        uint32(width),
+			Height:       uint32(height),
+			Pixelformat:  uint32(format),
+			Field:        config.field,
+			Bytesperline: uint32(config.bytesPerLine),
+			Sizeimage:    uint32(config.sizeImage),
+		},
+	}
+
+	b := toBytes(f)
+
+	if err := dev.ioctl(VIDIOC_S_FMT, toUintptr(b)); err != nil {
+		return f.Pix, err
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return f.Pix, err
+	}
+
+	// Drivers substitute a format of their own for one they do not offer.
+	// The device captures in it from then on, which needs a converter.
+	if actual := Format(f.Pix.Pixelformat); actual != format && lookupConverter(actual) == nil && !actual.Compressed() {
+		return f.Pix, fmt.Errorf("Driver set unsupported format %v instead of %v", actual, format)
+	}
+
+	if config.bytesPerLine != 0 && int(f.Pix.Bytesperline) != config.bytesPerLine {
+		return f.Pix, fmt.Errorf("Driver set %d bytes per line instead of %d", f.Pix.Bytesperline, config.bytesPerLine)
+	}
+
+	if int(f.Pix.Sizeimage) < config.sizeImage {
+		return f.Pix, fmt.Errorf("Driver set an image size of %d instead of %d", f.Pix.Sizeimage, config.sizeImage)
+	}
+
+	return f.Pix, nil
+
+}
+
+func (dev *handle) getFormat() (v4l2_pix_format, error) {
+
+	f := v4l2_format{Type: uint32(V4L2_BUF_TYPE_VIDEO_CAPTURE)}
+
+	b := toBytes(f)
+
+	if err := dev.ioctl(VIDIOC_G_FMT, toUintptr(b)); err != nil {
+		return f.Pix, err
+	}
+
+	if err := fromBytes(b, &f); err != nil {
+		return f.Pix, err
+	}
+
+	return f.Pix, nil
+}
+
+func (dev *handle) setUserptr(bufType uint32, config config) error {
+
+	if dev.readIO {
+		return nil
+	}
+
+	r := v4l2_requestbuffers{
+		Count:  1,
+		Type:   bufType,
+		Memory: V4L2_MEMORY_USERPTR,
+	}
+
+	b := toBytes(r)
+
+	if err := dev.ioctl(VIDIOC_REQBUFS, toUintptr(b)); err != nil {
+		return err
+	}
+
+	want := r.Count
+
+	if err := fromBytes(b, &r); err != nil {
+		return err
+	}
+
+	// The driver lowers the count to what it can allocate, down to zero.
+	// Capture only ever queues one buffer, so any grant will do.
+	if r.Count == 0 {
+		return fmt.Errorf("Driver granted none of %d buffers", want)
+	}
+	if r.Count < want {
+		dev.logf("Driver granted %d of %d buffers", r.Count, want)
+	}
+
+	dev.buffers = int(r.Count)
+
+	if config.noStreamOn {
+		dev.streaming = true
+		return nil
+	}
+
+	b2 := toBytes(bufType)
+
+	err := dev.ioctl(VIDIOC_STREAMON, toUintptr(b2))
+	for i := 0; i < config.streamRetries && (errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EBUSY)); i++ {
+		time.Sleep(config.streamDelay)
+		err = dev.ioctl(VIDIOC_STREAMON, toUintptr(b2))
+	}
+
+	dev.streaming = err == nil
+
+	return err
+}
+
+func toBytes(i interface{}) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, i)
+	return buf.Bytes()
+}
+
+func fromBytes(b []byte, i interface{}) error {
+	return binary.Read(bytes.NewReader(b), binary.LittleEndian, i)
+}
+
+func toUintptr(b []byte) uintptr {
+	return (*reflect.SliceHeader)(unsafe.Pointer(&b)).Data
+}
+
+func ioctl(fd int, req, arg uintptr) error {
+	_, _, e := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, arg)
+	for e == syscall.EINTR {
+		_, _, e = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, arg)
+	}
+	if e != 0 {
+		return os.NewSyscallError("ioctl", e)
+	}
+	return nil
+}
+
+// Ioctl issues an arbitrary request on the device, for the ioctls the
+// package does not wrap. arg must point to memory laid out exactly as the
+// kernel expects for req; nothing is checked, so a wrong layout corrupts
+// memory. Interrupted calls are retried and the IO timeout applies as it
+// does to the package's own requests.
+func (dev *handle) Ioctl(req uintptr, arg unsafe.Pointer) error {
+
+	err := dev.ioctl(req, uintptr(arg))
+	runtime.KeepAlive(arg)
+
+	return err
 }
@@ -0,0 +1,295 @@
+package v4l
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// benchDevice is the capture device the benchmarks that need real hardware
+// run against, taken from V4L_DEVICE.
+func benchDevice(b *testing.B) string {
+
+	device := os.Getenv("V4L_DEVICE")
+	if device == "" {
+		b.Skip("set V4L_DEVICE to a capture device to run")
+	}
+
+	return device
+}
+
+// BenchmarkPrepareBuffers compares the capture latency of GetFrameInto with
+// and without WithPrepareBuffers. The gain shows on SoCs with non-coherent
+// caches, where QBUF otherwise does the cache maintenance of the buffer
+// every time.
+func BenchmarkPrepareBuffers(b *testing.B) {
+
+	device := benchDevice(b)
+
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"unprepared", nil},
+		{"prepared", []Option{WithPrepareBuffers()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+
+			dev, err := Open(device, 640, 480, bm.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer dev.Close()
+
+			im := image.NewRGBA(dev.bounds())
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := dev.GetFrameInto(im); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var benchSizes = []image.Point{{640, 480}, {1920, 1080}}
+
+var packed422Formats = []Format{V4L2_PIX_FMT_YUYV, V4L2_PIX_FMT_UYVY, V4L2_PIX_FMT_YVYU, V4L2_PIX_FMT_VYUY}
+
+// BenchmarkFrameToImage measures the packed 4:2:2 conversion GetFrame
+// runs, for each byte order at VGA and 1080p, on synthetic frames.
+func BenchmarkFrameToImage(b *testing.B) {
+
+	for _, format := range packed422Formats {
+		for _, size := range benchSizes {
+
+			w, h := size.X, size.Y
+
+			b.Run(fmt.Sprintf("%v/%dx%d", format, w, h), func(b *testing.B) {
+
+				frame := make([]byte, w*h*2)
+				for i := range frame {
+					frame[i] = uint8(i * 31)
+				}
+
+				l := packed422Layouts[format]
+				im := image.NewRGBA(image.Rect(0, 0, w, h))
+
+				b.SetBytes(int64(len(frame)))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					frameToImage(frame, l, identityLUT, im)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkGetFrame measures the whole capture path, from queueing the
+// buffer to the converted image, against the test pattern, whose frames
+// are due faster than they can be taken.
+func BenchmarkGetFrame(b *testing.B) {
+
+	for _, size := range benchSizes {
+
+		b.Run(fmt.Sprintf("%dx%d", size.X, size.Y), func(b *testing.B) {
+
+			dev, err := OpenTestPattern(PatternBars, size.X, size.Y, 1000000)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer dev.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := dev.GetFrame(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParallelConvert converts YUYV frames of growing size serially
+// and in bands, to find the resolution from which SetParallel pays off on
+// the machine it runs on.
+func BenchmarkParallelConvert(b *testing.B) {
+
+	for _, size := range []image.Point{{320, 240}, {640, 480}, {1280, 720}, {1920, 1080}, {3840, 2160}} {
+		for _, parallel := range []bool{false, true} {
+
+			mode := "serial"
+			if parallel {
+				mode = "parallel"
+			}
+
+			b.Run(fmt.Sprintf("%dx%d/%s", size.X, size.Y, mode), func(b *testing.B) {
+
+				dev := &Device{format: V4L2_PIX_FMT_YUYV, width: size.X, height: size.Y, scale: 1, parallel: parallel}
+
+				frame := make([]byte, size.X*size.Y*2)
+				for i := range frame {
+					frame[i] = uint8(i * 31)
+				}
+
+				im := image.NewRGBA(dev.bounds())
+
+				b.SetBytes(int64(len(frame)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					dev.convert(frame, im)
+				}
+			})
+		}
+	}
+}
+
+func TestPacked422Orders(t *testing.T) {
+
+	const y0, y1, cb, cr = 60, 200, 90, 170
+
+	for _, c := range []struct {
+		format Format
+		frame  []byte
+	}{
+		{V4L2_PIX_FMT_YUYV, []byte{y0, cb, y1, cr}},
+		{V4L2_PIX_FMT_UYVY, []byte{cb, y0, cr, y1}},
+		{V4L2_PIX_FMT_YVYU, []byte{y0, cr, y1, cb}},
+		{V4L2_PIX_FMT_VYUY, []byte{cr, y0, cb, y1}},
+	} {
+
+		im := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		frameToImage(c.frame, packed422Layouts[c.format], identityLUT, im)
+
+		for x, y := range []uint8{y0, y1} {
+			r, g, b := color.YCbCrToRGB(y, cb, cr)
+			if got := im.RGBAAt(x, 0); got.R != r || got.G != g || got.B != b {
+				t.Errorf("%v pixel %d: got %v, want %d,%d,%d", c.format, x, got, r, g, b)
+			}
+		}
+	}
+}
+
+func TestNV21ChromaOrder(t *testing.T) {
+
+	const y, cb, cr = 120, 60, 200
+
+	// One 2x2 block sharing a chroma pair, Cb first for NV12 and Cr first
+	// for NV21.
+	nv12 := []byte{y, y, y, y, cb, cr}
+	nv21 := []byte{y, y, y, y, cr, cb}
+
+	r, g, b := color.YCbCrToRGB(y, cb, cr)
+
+	for _, c := range []struct {
+		swapped bool
+		frame   []byte
+	}{
+		{false, nv12},
+		{true, nv21},
+	} {
+
+		im := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		nv12ToImage(c.frame, 2, 2, 1, c.swapped, identityLUT, im)
+
+		if got := im.RGBAAt(1, 1); got.R != r || got.G != g || got.B != b {
+			t.Errorf("swapped %v: got %v, want %d,%d,%d", c.swapped, got, r, g, b)
+		}
+	}
+}
+
+func TestImageSizeFor(t *testing.T) {
+
+	for _, c := range []struct {
+		format        Format
+		width, height int
+		bytesperline  int
+		want          int
+	}{
+		{V4L2_PIX_FMT_YUYV, 640, 480, 0, 640 * 2 * 480},
+		{V4L2_PIX_FMT_UYVY, 640, 480, 1536, 1536 * 480},
+		{V4L2_PIX_FMT_RGB24, 640, 480, 0, 640 * 3 * 480},
+		{V4L2_PIX_FMT_BGR24, 640, 480, 100, 640 * 3 * 480},
+		{V4L2_PIX_FMT_RGB32, 640, 480, 0, 640 * 4 * 480},
+		{V4L2_PIX_FMT_RGBA32, 320, 240, 0, 320 * 4 * 240},
+		{V4L2_PIX_FMT_Y210, 320, 240, 0, 320 * 4 * 240},
+		{V4L2_PIX_FMT_Y16, 320, 240, 0, 320 * 2 * 240},
+		{V4L2_PIX_FMT_NV12, 640, 480, 0, 640 * 480 * 3 / 2},
+		{V4L2_PIX_FMT_NV21, 640, 481, 0, 640 * (481 + 241)},
+		{V4L2_PIX_FMT_NV12, 640, 480, 768, 768 * 480 * 3 / 2},
+		{V4L2_PIX_FMT_SRGGB10P, 640, 480, 0, 800 * 480},
+		{V4L2_PIX_FMT_MJPEG, 640, 480, 0, 640 * 2 * 480},
+		{FourCC("XXXX"), 640, 480, 0, 0},
+	} {
+		if got := ImageSizeFor(c.format, c.width, c.height, c.bytesperline); got != c.want {
+			t.Errorf("%v %dx%d, %d bytes per line: got %d, want %d", c.format, c.width, c.height, c.bytesperline, got, c.want)
+		}
+	}
+}
+
+func TestFrameSizeFallsBackToSizeimage(t *testing.T) {
+
+	dev := &Device{format: FourCC("XXXX"), width: 640, height: 480, bufType: V4L2_BUF_TYPE_VIDEO_CAPTURE}
+	dev.pix.Sizeimage = 123456
+
+	if got := dev.frameSize(); got != 123456 {
+		t.Errorf("frame size %d, want the driver's 123456", got)
+	}
+}
+
+func TestBitsPerPixel(t *testing.T) {
+
+	for format, want := range map[Format]int{
+		V4L2_PIX_FMT_YUYV:     16,
+		V4L2_PIX_FMT_NV12:     12,
+		V4L2_PIX_FMT_RGB24:    24,
+		V4L2_PIX_FMT_BGR24:    24,
+		V4L2_PIX_FMT_RGB32:    32,
+		V4L2_PIX_FMT_RGBA32:   32,
+		V4L2_PIX_FMT_SRGGB10P: 10,
+		V4L2_PIX_FMT_MJPEG:    0,
+	} {
+		if got := format.BitsPerPixel(); got != want {
+			t.Errorf("%v: %d bits per pixel, want %d", format, got, want)
+		}
+	}
+}
+
+func TestParallelConvertScaled(t *testing.T) {
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	for _, format := range []Format{V4L2_PIX_FMT_YUYV, V4L2_PIX_FMT_RGBA32, V4L2_PIX_FMT_Y210, V4L2_PIX_FMT_Y16} {
+		for _, c := range []struct{ width, height, scale int }{{64, 48, 2}, {66, 50, 3}, {11, 11, 4}} {
+
+			serial := &Device{format: format, width: c.width, height: c.height, scale: c.scale}
+			parallel := &Device{format: format, width: c.width, height: c.height, scale: c.scale, parallel: true}
+
+			frame := make([]byte, serial.stride()*c.height)
+			for i := range frame {
+				frame[i] = uint8(i * 31)
+			}
+
+			want := image.NewRGBA(serial.bounds())
+			serial.convert(frame, want)
+
+			got := image.NewRGBA(parallel.bounds())
+			parallel.convert(frame, got)
+
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("%v %dx%d at scale %d: banded conversion differs from serial", format, c.width, c.height, c.scale)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package v4l
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	V4L2_EVENT_CTRL uint32 = 3
+
+	V4L2_EVENT_CTRL_CH_VALUE uint32 = 0x0001
+	V4L2_EVENT_CTRL_CH_FLAGS        = 0x0002
+	V4L2_EVENT_CTRL_CH_RANGE        = 0x0004
+
+	V4L2_EVENT_SUB_FL_SEND_INITIAL   uint32 = 0x0001
+	V4L2_EVENT_SUB_FL_ALLOW_FEEDBACK        = 0x0002
+)
+
+// watchInterval bounds how long a control watch waits for an event before
+// checking whether it was stopped.
+const watchInterval = 100 * time.Millisecond
+
+type controlWatch struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// WatchControl reports the value of control id on the returned channel
+// whenever it changes, so that a UI can follow changes made by other
+// programs such as a desktop camera settings app. The current value is
+// sent first. The control is watched through a file handle of its own,
+// on which capture never takes events, so changes made through the
+// Device are reported too. A consumer that falls behind only sees the
+// latest value. The channel is closed when the Device is closed or the
+// device goes away.
+func (dev *Device) WatchControl(id uint32) (<-chan int32, error) {
+
+	fd, err := dev.sys.Open(dev.device, os.O_RDWR|syscall.O_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open device: %w", busyError(err))
+	}
+
+	h := &handle{device: dev.device, fd: fd, sys: dev.sys, logger: dev.logger}
+
+	s := v4l2_event_subscription{Type: V4L2_EVENT_CTRL, Id: id, Flags: V4L2_EVENT_SUB_FL_SEND_INITIAL}
+
+	if err := h.ioctl(VIDIOC_SUBSCRIBE_EVENT, toUintptr(toBytes(s))); err != nil {
+		dev.sys.Close(fd)
+		return nil, fmt.Errorf("Failed to subscribe to control events: %v", err.Error())
+	}
+
+	w := &controlWatch{stop: make(chan struct{}), done: make(chan struct{})}
+	c := make(chan int32, 1)
+
+	dev.mu.Lock()
+	dev.watches = append(dev.watches, w)
+	dev.mu.Unlock()
+
+	go w.run(h, c)
+
+	return c, nil
+}
+
+func (w *controlWatch) run(h *handle, c chan int32) {
+
+	defer close(w.done)
+	defer close(c)
+	defer h.sys.Close(h.fd)
+
+	for {
+
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		revents, err := h.sys.Poll(h.fd, POLLPRI, watchInterval)
+		if err == ErrTimeout {
+			continue
+		}
+		if err != nil || revents&POLLERR != 0 {
+			return
+		}
+
+		b := toBytes(v4l2_event{})
+
+		if err := h.ioctl(VIDIOC_DQEVENT, toUintptr(b)); err != nil {
+			return
+		}
+
+		var e v4l2_event
+		if err := fromBytes(b, &e); err != nil {
+			return
+		}
+
+		// The v4l2_event_ctrl payload: changes, type, then the value
+		// union at offset 8.
+		changes := binary.LittleEndian.Uint32(e.U[0:])
+		if e.Type != V4L2_EVENT_CTRL || changes&V4L2_EVENT_CTRL_CH_VALUE == 0 {
+			continue
+		}
+
+		v := int32(binary.LittleEndian.Uint32(e.U[8:]))
+
+		select {
+		case <-c:
+		default:
+		}
+		c <- v
+	}
+}
+
+// stopWatches ends every control watch and waits for them to close their
+// file handles.
+func (dev *Device) stopWatches() {
+
+	dev.mu.Lock()
+	watches := dev.watches
+	dev.watches = nil
+	dev.mu.Unlock()
+
+	for _, w := range watches {
+		close(w.stop)
+		<-w.done
+	}
+}
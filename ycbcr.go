@@ -0,0 +1,141 @@
+package v4l
+
+import (
+	"fmt"
+	"image"
+)
+
+// GetFrameYCbCrInto captures a frame and copies its samples into dst
+// without converting them to RGB. Packed 4:2:2 formats such as YUYV need a
+// 4:2:2 dst and NV12 and NV21 a 4:2:0 one, so that the chroma is copied as
+// captured rather than resampled; dst must have the capture's bounds and
+// is filled untransformed. The capture buffer is reused across calls, so
+// nothing is allocated, which suits encoders taking YCbCr at high frame
+// rates. It is the YCbCr counterpart of GetFrameInto.
+func (dev *Device) GetFrameYCbCrInto(dst *image.YCbCr) error {
+
+	if err := dev.checkYCbCr(dst); err != nil {
+		return err
+	}
+
+	for {
+
+		buf := dev.spareBuffer()
+
+		_, err := dev.readFrameInto(buf)
+		if err == errSourceChanged {
+			dev.putBuffer(buf)
+			if err := dev.checkYCbCr(dst); err != nil {
+				return err
+			}
+			continue
+		}
+		if err == nil {
+			err = dev.ycbcrInto(buf, dst)
+		}
+
+		dev.putBuffer(buf)
+
+		return err
+	}
+}
+
+// checkYCbCr checks that dst can hold the device's frames as
+// GetFrameYCbCrInto copies them.
+func (dev *Device) checkYCbCr(dst *image.YCbCr) error {
+
+	var ratio image.YCbCrSubsampleRatio
+
+	if _, ok := packed422Layouts[dev.format]; ok {
+		ratio = image.YCbCrSubsampleRatio422
+	} else if semiPlanar(dev.format) {
+		ratio = image.YCbCrSubsampleRatio420
+	} else {
+		return fmt.Errorf("Unsupported format for YCbCr: %v", dev.format)
+	}
+
+	if dst.SubsampleRatio != ratio {
+		return fmt.Errorf("Image subsample ratio %v does not match the capture %v", dst.SubsampleRatio, ratio)
+	}
+
+	r := image.Rect(0, 0, dev.width, dev.height)
+	if dst.Rect != r {
+		return fmt.Errorf("Image bounds %v do not match the capture %v", dst.Rect, r)
+	}
+
+	cw, ch := (dev.width+1)/2, dev.height
+	if ratio == image.YCbCrSubsampleRatio420 {
+		ch = (dev.height + 1) / 2
+	}
+
+	if dst.YStride < dev.width || len(dst.Y) < (dev.height-1)*dst.YStride+dev.width {
+		return fmt.Errorf("Image luma plane too small for %dx%d", dev.width, dev.height)
+	}
+
+	cSize := (ch-1)*dst.CStride + cw
+	if dst.CStride < cw || len(dst.Cb) < cSize || len(dst.Cr) < cSize {
+		return fmt.Errorf("Image chroma planes too small for %dx%d", dev.width, dev.height)
+	}
+
+	return nil
+}
+
+func (dev *Device) ycbcrInto(frame []byte, dst *image.YCbCr) error {
+
+	if err := dev.checkYCbCr(dst); err != nil {
+		return err
+	}
+
+	width, height, stride := dev.width, dev.height, dev.stride()
+
+	if l, ok := packed422Layouts[dev.format]; ok {
+
+		frame = padFrame(frame, stride*height)
+
+		for y := 0; y < height; y++ {
+
+			row := frame[y*stride:]
+			ys := dst.Y[y*dst.YStride:]
+			cb := dst.Cb[y*dst.CStride:]
+			cr := dst.Cr[y*dst.CStride:]
+
+			for x := 0; x < width; x += 2 {
+				i := x * 2
+				ys[x] = row[i+l.y0]
+				if x+1 < width {
+					ys[x+1] = row[i+l.y1]
+				}
+				cb[x/2], cr[x/2] = row[i+l.cb], row[i+l.cr]
+			}
+		}
+
+		return nil
+	}
+
+	ch := (height + 1) / 2
+	frame = padFrame(frame, stride*(height+ch))
+
+	for y := 0; y < height; y++ {
+		copy(dst.Y[y*dst.YStride:y*dst.YStride+width], frame[y*stride:])
+	}
+
+	u, v := 0, 1
+	if dev.format == V4L2_PIX_FMT_NV21 {
+		u, v = 1, 0
+	}
+
+	chroma := frame[stride*height:]
+
+	for y := 0; y < ch; y++ {
+
+		row := chroma[y*stride:]
+		cb := dst.Cb[y*dst.CStride:]
+		cr := dst.Cr[y*dst.CStride:]
+
+		for x := 0; x < (width+1)/2; x++ {
+			cb[x], cr[x] = row[x*2+u], row[x*2+v]
+		}
+	}
+
+	return nil
+}